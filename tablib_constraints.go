@@ -0,0 +1,251 @@
+package tablib
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// Constraint represents a named constraint on the values of a column.
+// Several constraints can be attached to a column with AddConstraints, each of
+// them producing its own ValidationError.
+// Except NotNull, the built-in constraints accept nil values.
+type Constraint struct {
+	// Name identifies the constraint, such as "range".
+	Name string
+	// Message describes the constraint, such as "must be between 1 and 10".
+	Message string
+	// validate returns whether each value of a column satisfies the constraint.
+	validate func(values []interface{}) []bool
+}
+
+// NewConstraint creates a constraint checking each value with a function.
+func NewConstraint(name, message string, check func(interface{}) bool) Constraint {
+	return Constraint{name, message, func(values []interface{}) []bool {
+		valid := make([]bool, len(values))
+		for i, v := range values {
+			valid[i] = check(v)
+		}
+		return valid
+	}}
+}
+
+// nullable creates a constraint accepting nil values and checking the other
+// values with a function.
+func nullable(name, message string, check func(interface{}) bool) Constraint {
+	return NewConstraint(name, message, func(v interface{}) bool {
+		return v == nil || check(v)
+	})
+}
+
+// NotNull is a constraint rejecting nil values.
+func NotNull() Constraint {
+	return NewConstraint("not_null", "must not be null", func(v interface{}) bool {
+		return v != nil
+	})
+}
+
+// OfType is a constraint accepting only the values of a given type. Any integer
+// type is accepted for ColumnTypeInt, and any float type for ColumnTypeFloat.
+func OfType(t ColumnType) Constraint {
+	return nullable("of_type", "must be of type "+t.String(), func(v interface{}) bool {
+		switch t {
+		case ColumnTypeInt:
+			switch v.(type) {
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+				return true
+			}
+		case ColumnTypeFloat:
+			k := reflect.TypeOf(v).Kind()
+			return k == reflect.Float32 || k == reflect.Float64
+		case ColumnTypeBool:
+			_, ok := v.(bool)
+			return ok
+		case ColumnTypeTime:
+			_, ok := v.(time.Time)
+			return ok
+		case ColumnTypeString:
+			_, ok := v.(string)
+			return ok
+		}
+		return false
+	})
+}
+
+// Range is a constraint accepting only the values between min and max inclusive,
+// as ordered by Compare. Values must be of the same kind as min and max, any
+// number being comparable to any number.
+func Range(min, max interface{}) Constraint {
+	return nullable("range", fmt.Sprintf("must be between %v and %v", min, max), func(v interface{}) bool {
+		return kindOf(v) == kindOf(min) && Compare(min, v) <= 0 && Compare(v, max) <= 0
+	})
+}
+
+// Regexp is a constraint accepting only the strings matching a regular expression.
+func Regexp(re *regexp.Regexp) Constraint {
+	return nullable("regexp", "must match "+re.String(), func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && re.MatchString(s)
+	})
+}
+
+// OneOf is a constraint accepting only a given set of values. Numbers with
+// the same value are considered equal whatever their type.
+func OneOf(values ...interface{}) Constraint {
+	accepted := make(map[string]bool, len(values))
+	for _, v := range values {
+		accepted[hashKey(v)] = true
+	}
+	return nullable("one_of", fmt.Sprintf("must be one of %v", values), func(v interface{}) bool {
+		return accepted[hashKey(v)]
+	})
+}
+
+// MaxLength is a constraint accepting only the strings having at most n characters.
+func MaxLength(n int) Constraint {
+	return nullable("max_length", fmt.Sprintf("must be at most %d characters long", n), func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && utf8.RuneCountInString(s) <= n
+	})
+}
+
+// DateBetween is a constraint accepting only the time.Time values between from
+// and to inclusive.
+func DateBetween(from, to time.Time) Constraint {
+	message := fmt.Sprintf("must be between %s and %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return nullable("date_between", message, func(v interface{}) bool {
+		t, ok := v.(time.Time)
+		return ok && !t.Before(from) && !t.After(to)
+	})
+}
+
+// Unique is a constraint rejecting the values already present in the previous
+// rows of the column. Numbers with the same value are considered equal whatever
+// their type. Being checked on the whole column, it is ignored by the row by row
+// validations of CSVReader and CSVWriter.
+func Unique() Constraint {
+	return Constraint{"unique", "must be unique", func(values []interface{}) []bool {
+		valid := make([]bool, len(values))
+		seen := make(map[string]bool, len(values))
+		for i, v := range values {
+			key := hashKey(v)
+			valid[i] = v == nil || !seen[key]
+			seen[key] = true
+		}
+		return valid
+	}}
+}
+
+// AnyOf is a constraint accepting the values satisfying at least one of the
+// given constraints.
+func AnyOf(constraints ...Constraint) Constraint {
+	message := ""
+	for i, c := range constraints {
+		if i > 0 {
+			message += " or "
+		}
+		message += c.Message
+	}
+	return Constraint{"any_of", message, func(values []interface{}) []bool {
+		valid := make([]bool, len(values))
+		for _, c := range constraints {
+			for i, ok := range c.validate(values) {
+				valid[i] = valid[i] || ok
+			}
+		}
+		return valid
+	}}
+}
+
+// RowConstraint represents a named constraint on whole rows, such as
+// "end must be after start", checked in addition to the column constraints.
+type RowConstraint struct {
+	// Name identifies the constraint, such as "end_after_start".
+	Name string
+	// Message describes the constraint, such as "end must be after start".
+	Message string
+	// Columns are the headers of the columns participating in the constraint.
+	// The whole row participates if empty.
+	Columns []string
+	// Check returns whether a row, given by header, satisfies the constraint.
+	Check Predicate
+}
+
+// NewRowConstraint creates a constraint on whole rows, involving some columns.
+func NewRowConstraint(name, message string, check Predicate, columns ...string) RowConstraint {
+	return RowConstraint{name, message, columns, check}
+}
+
+// AddRowConstraints attaches constraints on whole rows to the Dataset, in addition
+// to the ones already attached.
+func (d *Dataset) AddRowConstraints(constraints ...RowConstraint) {
+	d.rowConstraints = append(d.rowConstraints, constraints...)
+}
+
+// RowConstraints returns the constraints on whole rows attached to the Dataset.
+func (d *Dataset) RowConstraints() []RowConstraint {
+	return d.rowConstraints
+}
+
+// checkRowConstraint checks a row constraint on the evaluated row at a given
+// index, returning the ValidationError in case of failure.
+func (d *Dataset) checkRowConstraint(c RowConstraint, index int, row []interface{}) (ValidationError, bool) {
+	r := make(Row, d.cols)
+	for j, v := range row {
+		r[d.headers[j]] = v
+	}
+	if c.Check(r) {
+		return ValidationError{}, true
+	}
+
+	columns := c.Columns
+	if len(columns) == 0 {
+		columns = d.headers
+	}
+	value := make(Row, len(columns))
+	for _, h := range columns {
+		value[h] = r[h]
+	}
+	return ValidationError{index, -1, "", columns, c.Name, c.Message, value}, false
+}
+
+// AddConstraints attaches constraints to a column, in addition to the ones
+// already attached. Returns ErrInvalidColumnIndex if the column does not exist.
+func (d *Dataset) AddConstraints(header string, constraints ...Constraint) error {
+	if indexOfColumn(header, d) == -1 {
+		return ErrInvalidColumnIndex
+	}
+	if d.namedConstraints == nil {
+		d.namedConstraints = make(map[string][]Constraint)
+	}
+	d.namedConstraints[header] = append(d.namedConstraints[header], constraints...)
+	return nil
+}
+
+// Constraints returns the constraints attached to a column with AddConstraints.
+func (d *Dataset) Constraints(header string) []Constraint {
+	return d.namedConstraints[header]
+}
+
+// constraintsAt returns all the constraints of a column, including the
+// ColumnConstraint set with ConstrainColumn.
+func (d *Dataset) constraintsAt(column int) []Constraint {
+	constraints := make([]Constraint, 0)
+	if fn := d.constraints[column]; fn != nil {
+		constraints = append(constraints, NewConstraint("constraint", "must satisfy the column constraint", fn))
+	}
+	return append(constraints, d.namedConstraints[d.headers[column]]...)
+}
+
+// copyConstraintsTo copies the constraints of the Dataset to another Dataset
+// having the same headers, possibly followed by other ones.
+func (d *Dataset) copyConstraintsTo(nd *Dataset) {
+	copy(nd.constraints, d.constraints)
+	for header, constraints := range d.namedConstraints {
+		nd.AddConstraints(header, constraints...)
+	}
+	nd.AddRowConstraints(d.rowConstraints...)
+	nd.keys = d.keys.clone()
+}
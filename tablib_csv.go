@@ -1,19 +1,124 @@
 package tablib
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// QuotePolicy represents when fields are quoted while exporting to CSV.
+type QuotePolicy int
+
+const (
+	// QuoteMinimal quotes only the fields containing special characters.
+	QuoteMinimal QuotePolicy = iota
+	// QuoteAll quotes every field.
+	QuoteAll
+	// QuoteNonNumeric quotes every field which is not a number.
+	QuoteNonNumeric
+	// QuoteNone never quotes fields, ErrUnquotableField is returned when a
+	// field would need to be quoted.
+	QuoteNone
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// CSVOptions represents a CSV dialect, used both when loading and exporting.
+// The zero value represents the standard comma separated format.
+type CSVOptions struct {
+	// Delimiter is the field delimiter, ',' if not set.
+	Delimiter rune
+	// Quote is the quote policy used on export.
+	Quote QuotePolicy
+	// Comment, if set, is the character starting comment lines ignored on load.
+	Comment rune
+	// LazyQuotes allows quotes to appear in unquoted fields and non doubled
+	// quotes in quoted fields on load.
+	LazyQuotes bool
+	// TrimLeadingSpace ignores the leading white spaces of the fields on load.
+	TrimLeadingSpace bool
+	// NoHeader indicates that there is no header row, on load the headers are
+	// taken from Headers, and on export the header row is not written.
+	NoHeader bool
+	// Headers are the headers used on load when NoHeader is set. They are
+	// generated as "Column 1", "Column 2"... if empty.
+	Headers []string
+	// UseCRLF uses \r\n as line terminator on export instead of \n.
+	// Both are always accepted on load.
+	UseCRLF bool
+	// WriteBOM writes a UTF-8 byte order mark on export.
+	// A byte order mark is always skipped on load.
+	WriteBOM bool
+	// Tags restores on load the tags of the rows from the TagsColumn column,
+	// if any, which is then removed.
+	Tags bool
+	// Schema, if set, is attached to the loaded Dataset, see Dataset.SetSchema.
+	Schema Schema
+}
+
+// delimiter returns the delimiter to use.
+func (o CSVOptions) delimiter() rune {
+	if o.Delimiter == 0 {
+		return ','
+	}
+	return o.Delimiter
+}
+
+// headers returns the headers to use for records of a given width when NoHeader is set.
+func (o CSVOptions) headers(width int) []string {
+	if len(o.Headers) > 0 {
+		return o.Headers
+	}
+	headers := make([]string, width)
+	for i := range headers {
+		headers[i] = "Column " + strconv.Itoa(i+1)
+	}
+	return headers
+}
+
+// newReader creates a csv.Reader configured for the dialect, skipping any byte order mark.
+func (o CSVOptions) newReader(r io.Reader) *csv.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = o.delimiter()
+	reader.Comment = o.Comment
+	reader.LazyQuotes = o.LazyQuotes
+	reader.TrimLeadingSpace = o.TrimLeadingSpace
+	return reader
+}
+
 // CSV returns a CSV representation of the Dataset an Exportable.
 func (d *Dataset) CSV() (*Exportable, error) {
+	return d.CSVWithOptions(CSVOptions{})
+}
+
+// CSVWithOptions returns a CSV representation of the Dataset as an Exportable,
+// using a specific dialect.
+func (d *Dataset) CSVWithOptions(opts CSVOptions) (*Exportable, error) {
 	records := d.Records()
+	if opts.NoHeader {
+		records = records[1:]
+	}
 	b := newBuffer()
 
-	w := csv.NewWriter(b)
-	w.WriteAll(records) // calls Flush internally
+	w := newCSVRecordWriter(b, opts)
+	for _, r := range records {
+		if err := w.write(r); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := w.Error(); err != nil {
+	if err := w.flush(); err != nil {
 		return nil, err
 	}
 
@@ -22,20 +127,30 @@ func (d *Dataset) CSV() (*Exportable, error) {
 
 // LoadCSV loads a Dataset by its CSV representation.
 func LoadCSV(input []byte) (*Dataset, error) {
-	reader := csv.NewReader(bytes.NewReader(input))
-	records, err := reader.ReadAll()
+	return LoadCSVWithOptions(input, CSVOptions{})
+}
+
+// LoadCSVWithOptions loads a Dataset by its CSV representation, using a specific dialect.
+func LoadCSVWithOptions(input []byte, opts CSVOptions) (*Dataset, error) {
+	r, err := NewCSVReaderWithOptions(bytes.NewReader(input), opts)
+	if err == io.EOF {
+		return NewDataset(opts.Headers), nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	ds := NewDataset(records[0])
-	for i := 1; i < len(records); i++ {
-		// this is odd
-		row := make([]interface{}, len(records[i]))
-		for k, v := range records[i] {
-			row[k] = v
+	ds := NewDataset(r.Headers())
+	for r.Next() {
+		ds.AppendTagged(r.row, r.tags...)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Schema != nil {
+		if err := ds.SetSchema(opts.Schema); err != nil {
+			return nil, err
 		}
-		ds.Append(row)
 	}
 
 	return ds, nil
@@ -43,39 +158,117 @@ func LoadCSV(input []byte) (*Dataset, error) {
 
 // TSV returns a TSV representation of the Dataset as string.
 func (d *Dataset) TSV() (*Exportable, error) {
-	records := d.Records()
-	b := newBuffer()
+	return d.CSVWithOptions(CSVOptions{Delimiter: '\t'})
+}
 
-	w := csv.NewWriter(b)
-	w.Comma = '\t'
-	w.WriteAll(records) // calls Flush internally
+// LoadTSV loads a Dataset by its TSV representation.
+func LoadTSV(input []byte) (*Dataset, error) {
+	return LoadCSVWithOptions(input, CSVOptions{Delimiter: '\t'})
+}
 
-	if err := w.Error(); err != nil {
-		return nil, err
-	}
+// csvRecordWriter writes CSV records to an io.Writer honouring a CSVOptions dialect.
+type csvRecordWriter struct {
+	w          *bufio.Writer
+	opts       CSVOptions
+	bomWritten bool
+}
 
-	return newExportable(b), nil
+// newCSVRecordWriter creates a new csvRecordWriter.
+func newCSVRecordWriter(w io.Writer, opts CSVOptions) *csvRecordWriter {
+	return &csvRecordWriter{w: bufio.NewWriter(w), opts: opts}
 }
 
-// LoadTSV loads a Dataset by its TSV representation.
-func LoadTSV(input []byte) (*Dataset, error) {
-	reader := csv.NewReader(bytes.NewReader(input))
-	reader.Comma = '\t'
+// write writes a single record. With QuoteNone, nothing is written if a field
+// needs to be quoted.
+func (w *csvRecordWriter) write(record []string) error {
+	comma := w.opts.delimiter()
+	if w.opts.Quote == QuoteNone {
+		for _, field := range record {
+			if fieldNeedsQuotes(field, comma) {
+				return ErrUnquotableField
+			}
+		}
+	}
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
+	if w.opts.WriteBOM && !w.bomWritten {
+		w.w.Write(utf8BOM)
 	}
+	w.bomWritten = true
+
+	for i, field := range record {
+		if i > 0 {
+			w.w.WriteRune(comma)
+		}
 
-	ds := NewDataset(records[0])
-	for i := 1; i < len(records); i++ {
-		// this is odd
-		row := make([]interface{}, len(records[i]))
-		for k, v := range records[i] {
-			row[k] = v
+		quote := false
+		switch w.opts.Quote {
+		case QuoteAll:
+			quote = true
+		case QuoteNonNumeric:
+			quote = !isNumericField(field)
+		case QuoteNone:
+			// checked above
+		default:
+			quote = fieldNeedsQuotes(field, comma)
 		}
-		ds.Append(row)
+
+		if !quote {
+			w.w.WriteString(field)
+			continue
+		}
+
+		w.w.WriteByte('"')
+		for _, r := range field {
+			switch r {
+			case '"':
+				w.w.WriteString(`""`)
+			case '\r':
+				if !w.opts.UseCRLF {
+					w.w.WriteByte('\r')
+				}
+			case '\n':
+				if w.opts.UseCRLF {
+					w.w.WriteString("\r\n")
+				} else {
+					w.w.WriteByte('\n')
+				}
+			default:
+				w.w.WriteRune(r)
+			}
+		}
+		w.w.WriteByte('"')
 	}
 
-	return ds, nil
+	var err error
+	if w.opts.UseCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+// flush writes any buffered data to the underlying io.Writer.
+func (w *csvRecordWriter) flush() error {
+	return w.w.Flush()
+}
+
+// isNumericField reports whether a field is a finite number.
+func isNumericField(field string) bool {
+	f, err := strconv.ParseFloat(field, 64)
+	return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// fieldNeedsQuotes reports whether a field must be quoted, following the
+// same rules as encoding/csv.
+func fieldNeedsQuotes(field string, comma rune) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsRune(field, comma) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
 }
@@ -0,0 +1,236 @@
+package tablib
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SQLLoadOptions holds the options used when loading a Dataset from database/sql rows.
+type SQLLoadOptions struct {
+	// Limit is the maximum number of rows to load, all of them if not set.
+	Limit int
+}
+
+// QueryDataset runs a query returning rows and loads them into a Dataset,
+// see LoadSQLRows.
+func QueryDataset(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*Dataset, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSQLRowsWithOptions(ctx, rows, SQLLoadOptions{})
+}
+
+// LoadSQLRows loads a Dataset from database/sql rows, which are closed afterwards.
+// The headers are the names of the columns, and the values are scanned into the
+// Go type reported by the driver for their column, NULL values and invalid
+// sql.Null* values being loaded as nil, and []byte values as strings.
+func LoadSQLRows(rows *sql.Rows) (*Dataset, error) {
+	return LoadSQLRowsWithOptions(context.Background(), rows, SQLLoadOptions{})
+}
+
+// LoadSQLRowsWithOptions loads a Dataset from database/sql rows like LoadSQLRows,
+// using specific options. Returns the error of the context if it is done before
+// all the rows are loaded.
+func LoadSQLRowsWithOptions(ctx context.Context, rows *sql.Rows, opts SQLLoadOptions) (*Dataset, error) {
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]string, len(columnTypes))
+	scanTypes := make([]reflect.Type, len(columnTypes))
+	for i, ct := range columnTypes {
+		headers[i] = ct.Name()
+		scanTypes[i] = sqlScanType(ct)
+	}
+
+	ds := NewDataset(headers)
+	for opts.Limit <= 0 || ds.rows < opts.Limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !rows.Next() {
+			break
+		}
+		// scan into pointers to pointers, so that NULL values become nil pointers
+		dest := make([]interface{}, len(scanTypes))
+		for i, t := range scanTypes {
+			dest[i] = reflect.New(reflect.PtrTo(t)).Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]interface{}, len(dest))
+		for i, p := range dest {
+			row[i] = sqlScannedValue(reflect.ValueOf(p).Elem())
+		}
+		ds.AppendValues(row...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// sqlScanType returns the type into which the values of a column are scanned.
+func sqlScanType(ct *sql.ColumnType) reflect.Type {
+	t := ct.ScanType()
+	if t == nil || t == reflect.TypeOf(sql.RawBytes{}) {
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+	return t
+}
+
+// sqlScannedValue returns the value held by a pointer filled by Scan.
+func sqlScannedValue(p reflect.Value) interface{} {
+	if p.IsNil() {
+		return nil
+	}
+	v := p.Elem()
+	t := v.Type()
+	if t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null") {
+		// sql.NullString, sql.NullInt64, ... hold their value in their first field
+		if !v.FieldByName("Valid").Bool() {
+			return nil
+		}
+		v = v.Field(0)
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b)
+	}
+	return v.Interface()
+}
+
+// InsertOptions holds the options used when inserting a Dataset into a database.
+type InsertOptions struct {
+	// Dialect is the SQL dialect of the database.
+	Dialect SQLDialect
+	// CreateTable creates the table if it does not exist, like SQL does. The
+	// synthetic id column, if any, is filled by the database.
+	CreateTable bool
+	// BatchSize is the number of rows inserted by each transaction, 1000 if not set.
+	BatchSize int
+	// SkipInvalid skips the rows failing the constraints of the Dataset, as
+	// returned by InvalidSubset, instead of inserting them.
+	SkipInvalid bool
+}
+
+// InsertError represents the failure of the insertion of a row.
+type InsertError struct {
+	// Row is the index of the row in the Dataset.
+	Row int
+	// Err is the error returned by the database.
+	Err error
+}
+
+// Error returns a description of the InsertError.
+func (e InsertError) Error() string {
+	return fmt.Sprintf("tablib: row %d: %v", e.Row, e.Err)
+}
+
+// InsertResult holds the outcome of the insertion of a Dataset into a database.
+type InsertResult struct {
+	// Inserted is the number of inserted rows.
+	Inserted int
+	// Skipped are the indexes of the rows skipped because they are invalid.
+	Skipped []int
+	// Errors are the rows whose insertion failed, ordered by row.
+	Errors []InsertError
+}
+
+// InsertInto inserts the rows of the Dataset into a table of a database, by
+// transactions of opts.BatchSize rows using a prepared statement.
+// When the insertion of a row fails, its transaction is rolled back, the error
+// is reported in the InsertError of the row and the transaction is retried
+// without it, so that all the other rows get inserted.
+// Returns an error, along with the result so far, if the table cannot be created,
+// a transaction cannot be committed or the context is done.
+func (d *Dataset) InsertInto(ctx context.Context, db *sql.DB, table string, opts InsertOptions) (*InsertResult, error) {
+	result := &InsertResult{Skipped: make([]int, 0), Errors: make([]InsertError, 0)}
+	dialect := opts.Dialect
+
+	if opts.CreateTable {
+		tableSQL, _ := d.createTable(table, dialect, nil, nil)
+		if _, err := db.ExecContext(ctx, tableSQL); err != nil {
+			return result, err
+		}
+	}
+	statement, args := d.PreparedInsert(table, dialect)
+
+	invalidRows := make(map[int]bool)
+	if opts.SkipInvalid && d.HasAnyConstraint() {
+		for _, e := range d.validate(false) {
+			invalidRows[e.Row] = true
+		}
+	}
+	pending := make([]int, 0, d.rows)
+	for i := range args {
+		if invalidRows[i] {
+			result.Skipped = append(result.Skipped, i)
+		} else {
+			pending = append(pending, i)
+		}
+	}
+
+	size := opts.BatchSize
+	if size < 1 {
+		size = 1000
+	}
+	for start := 0; start < len(pending); start += size {
+		end := start + size
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		for len(batch) > 0 {
+			failed, err := insertBatch(ctx, db, statement, args, batch)
+			if err != nil {
+				return result, err
+			}
+			if failed == nil {
+				result.Inserted += len(batch)
+				break
+			}
+			result.Errors = append(result.Errors, *failed)
+			retried := make([]int, 0, len(batch)-1)
+			for _, i := range batch {
+				if i != failed.Row {
+					retried = append(retried, i)
+				}
+			}
+			batch = retried
+		}
+	}
+	return result, nil
+}
+
+// insertBatch inserts some rows within a transaction, which is rolled back at the
+// first row failing to be inserted, returned as an InsertError.
+func insertBatch(ctx context.Context, db *sql.DB, statement string, args [][]interface{}, rows []int) (*InsertError, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.PrepareContext(ctx, statement)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, i := range rows {
+		if _, err := stmt.ExecContext(ctx, args[i]...); err != nil {
+			tx.Rollback()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return &InsertError{i, err}, nil
+		}
+	}
+	return nil, tx.Commit()
+}
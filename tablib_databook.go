@@ -16,29 +16,99 @@ func (s Sheet) Dataset() *Dataset {
 	return s.dataset
 }
 
-// Databook represents a Databook which is an array of sheets.
+// Databook represents a Databook which is an ordered array of sheets.
 type Databook struct {
-	sheets map[string]Sheet
+	sheets []Sheet
 }
 
 // NewDatabook constructs a new Databook.
 func NewDatabook() *Databook {
-	return &Databook{make(map[string]Sheet)}
+	return &Databook{make([]Sheet, 0)}
 }
 
-// Sheets returns the sheets in the Databook.
+// Sheets returns the sheets in the Databook by title.
+// Use SheetAt or SheetTitles to access them in order.
 func (d *Databook) Sheets() map[string]Sheet {
-	return d.sheets
+	sheets := make(map[string]Sheet, len(d.sheets))
+	for _, s := range d.sheets {
+		sheets[s.title] = s
+	}
+	return sheets
+}
+
+// SheetTitles returns the titles of the sheets in the Databook, in order.
+func (d *Databook) SheetTitles() []string {
+	titles := make([]string, 0, len(d.sheets))
+	for _, s := range d.sheets {
+		titles = append(titles, s.title)
+	}
+	return titles
 }
 
 // Sheet returns the sheet with a specific title.
 func (d *Databook) Sheet(title string) Sheet {
-	return d.sheets[title]
+	if i := d.indexOfSheet(title); i != -1 {
+		return d.sheets[i]
+	}
+	return Sheet{}
 }
 
-// AddSheet adds a sheet to the Databook.
+// SheetAt returns the sheet at a given index.
+// Returns ErrInvalidSheet if the index is out of range.
+func (d *Databook) SheetAt(index int) (Sheet, error) {
+	if index < 0 || index >= len(d.sheets) {
+		return Sheet{}, ErrInvalidSheet
+	}
+	return d.sheets[index], nil
+}
+
+// AddSheet adds a sheet at the end of the Databook.
+// If a sheet with the same title exists, its dataset is replaced in place.
 func (d *Databook) AddSheet(title string, dataset *Dataset) {
-	d.sheets[title] = Sheet{title, dataset}
+	if i := d.indexOfSheet(title); i != -1 {
+		d.sheets[i].dataset = dataset
+		return
+	}
+	d.sheets = append(d.sheets, Sheet{title, dataset})
+}
+
+// RemoveSheet removes the sheet with a specific title.
+// Returns ErrInvalidSheet if the sheet does not exist.
+func (d *Databook) RemoveSheet(title string) error {
+	i := d.indexOfSheet(title)
+	if i == -1 {
+		return ErrInvalidSheet
+	}
+	d.sheets = append(d.sheets[:i], d.sheets[i+1:]...)
+	return nil
+}
+
+// RenameSheet renames the sheet with a specific title, keeping its position.
+// Returns ErrInvalidSheet if the sheet does not exist or if another sheet
+// already has the new title.
+func (d *Databook) RenameSheet(title, newTitle string) error {
+	i := d.indexOfSheet(title)
+	if i == -1 {
+		return ErrInvalidSheet
+	}
+	if j := d.indexOfSheet(newTitle); j != -1 && j != i {
+		return ErrInvalidSheet
+	}
+	d.sheets[i].title = newTitle
+	return nil
+}
+
+// MoveSheet moves the sheet with a specific title to a given index.
+// Returns ErrInvalidSheet if the sheet does not exist or if the index is out of range.
+func (d *Databook) MoveSheet(title string, index int) error {
+	i := d.indexOfSheet(title)
+	if i == -1 || index < 0 || index >= len(d.sheets) {
+		return ErrInvalidSheet
+	}
+	s := d.sheets[i]
+	d.sheets = append(d.sheets[:i], d.sheets[i+1:]...)
+	d.sheets = append(d.sheets[:index], append([]Sheet{s}, d.sheets[index:]...)...)
+	return nil
 }
 
 // Size returns the number of sheets in the Databook.
@@ -48,7 +118,15 @@ func (d *Databook) Size() int {
 
 // Wipe removes all Dataset objects from the Databook.
 func (d *Databook) Wipe() {
-	for k := range d.sheets {
-		delete(d.sheets, k)
+	d.sheets = make([]Sheet, 0)
+}
+
+// indexOfSheet returns the index of the sheet with a specific title, -1 if not found.
+func (d *Databook) indexOfSheet(title string) int {
+	for i, s := range d.sheets {
+		if s.title == title {
+			return i
+		}
 	}
+	return -1
 }
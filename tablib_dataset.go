@@ -6,7 +6,6 @@ package tablib
 import (
 	"fmt"
 	"sort"
-	"time"
 )
 
 // Dataset represents a set of data, which is a list of data and header for each column.
@@ -20,6 +19,11 @@ type Dataset struct {
 	constraints      []ColumnConstraint
 	rows             int
 	cols             int
+	formats          map[string]string
+	namedConstraints map[string][]Constraint
+	rowConstraints   []RowConstraint
+	keys             datasetKeys
+	fields           map[string]Field
 	ValidationErrors []ValidationError
 }
 
@@ -32,10 +36,36 @@ type DynamicColumn func([]interface{}) interface{}
 type ColumnConstraint func(interface{}) bool
 
 // ValidationError holds the position of a value in the Dataset that have failed
-// to validate a constraint.
+// to validate a constraint, and the reason why.
+// For a RowConstraint, Column is -1 and Header is empty. For a foreign key whose
+// columns do not exist, Row is -1 as well.
 type ValidationError struct {
 	Row    int
 	Column int
+	// Header is the header of the column.
+	Header string
+	// Columns are the headers of the columns participating in the failure.
+	Columns []string
+	// Constraint is the name of the failed constraint.
+	Constraint string
+	// Message describes the failed constraint.
+	Message string
+	// Value is the offending value, or the Row of the participating values for
+	// a RowConstraint.
+	Value interface{}
+}
+
+// Error returns a description of the ValidationError.
+func (e ValidationError) Error() string {
+	if e.Row == -1 {
+		return fmt.Sprintf("tablib: columns %q: %s (%s)", e.Columns, e.Message, e.Constraint)
+	}
+	if e.Column == -1 {
+		return fmt.Sprintf("tablib: row %d, columns %q: %s (%s)",
+			e.Row, e.Columns, e.Message, e.Constraint)
+	}
+	return fmt.Sprintf("tablib: row %d, column %q: %v %s (%s)",
+		e.Row, e.Header, e.Value, e.Message, e.Constraint)
 }
 
 // NewDataset creates a new Dataset.
@@ -45,8 +75,8 @@ func NewDataset(headers []string) *Dataset {
 
 // NewDatasetWithData creates a new Dataset.
 func NewDatasetWithData(headers []string, data [][]interface{}) *Dataset {
-	d := &Dataset{"", headers, data, make([][]string, 0), make([]ColumnConstraint,
-		len(headers)), len(data), len(headers), nil}
+	d := &Dataset{"", headers, data, make([][]string, len(data)), make([]ColumnConstraint,
+		len(headers)), len(data), len(headers), nil, nil, nil, datasetKeys{}, nil, nil}
 	return d
 }
 
@@ -66,10 +96,15 @@ func (d *Dataset) Height() int {
 }
 
 // Append appends a row of values to the Dataset.
+// The values are coerced to the Schema of the Dataset, if any.
 func (d *Dataset) Append(row []interface{}) error {
 	if len(row) != d.cols {
 		return ErrInvalidDimensions
 	}
+	row, err := d.coerceRow(d.fields, row)
+	if err != nil {
+		return err
+	}
 	d.data = append(d.data, row)
 	d.tags = append(d.tags, make([]string, 0))
 	d.rows++
@@ -109,6 +144,7 @@ func (d *Dataset) AppendValuesTagged(row ...interface{}) error {
 }
 
 // Insert inserts a row at a given index.
+// The values are coerced to the Schema of the Dataset, if any.
 func (d *Dataset) Insert(index int, row []interface{}) error {
 	if index < 0 || index >= d.rows {
 		return ErrInvalidRowIndex
@@ -117,6 +153,10 @@ func (d *Dataset) Insert(index int, row []interface{}) error {
 	if len(row) != d.cols {
 		return ErrInvalidDimensions
 	}
+	row, err := d.coerceRow(d.fields, row)
+	if err != nil {
+		return err
+	}
 
 	ndata := make([][]interface{}, 0, d.rows+1)
 	ndata = append(ndata, d.data[:index]...)
@@ -144,7 +184,6 @@ func (d *Dataset) InsertTagged(index int, row []interface{}, tags ...string) err
 	if err := d.Insert(index, row); err != nil {
 		return err
 	}
-	d.Insert(index, row)
 	d.tags[index] = tags[:]
 
 	return nil
@@ -177,10 +216,15 @@ func (d *Dataset) Tags(index int) ([]string, error) {
 }
 
 // AppendColumn appends a new column with values to the Dataset.
+// The values are coerced to the field of the Schema named after the column, if any.
 func (d *Dataset) AppendColumn(header string, cols []interface{}) error {
 	if len(cols) != d.rows {
 		return ErrInvalidDimensions
 	}
+	cols, err := d.coerceColumn(header, cols)
+	if err != nil {
+		return err
+	}
 	d.headers = append(d.headers, header)
 	d.constraints = append(d.constraints, nil) // no constraint by default
 	d.cols++
@@ -224,7 +268,25 @@ func (d *Dataset) ConstrainColumn(header string, constraint ColumnConstraint) {
 	}
 }
 
+// SetColumnFormat sets the number format of a column, such as "0.00%" or
+// "yyyy-mm-dd", used by the formats supporting it like XLSX.
+func (d *Dataset) SetColumnFormat(header, format string) {
+	if indexOfColumn(header, d) == -1 {
+		return
+	}
+	if d.formats == nil {
+		d.formats = make(map[string]string)
+	}
+	d.formats[header] = format
+}
+
+// ColumnFormat returns the number format set on a column, if any.
+func (d *Dataset) ColumnFormat(header string) string {
+	return d.formats[header]
+}
+
 // InsertColumn insert a new column at a given index.
+// The values are coerced to the field of the Schema named after the column, if any.
 func (d *Dataset) InsertColumn(index int, header string, cols []interface{}) error {
 	if index < 0 || index >= d.cols {
 		return ErrInvalidColumnIndex
@@ -233,6 +295,10 @@ func (d *Dataset) InsertColumn(index int, header string, cols []interface{}) err
 	if len(cols) != d.rows {
 		return ErrInvalidDimensions
 	}
+	cols, err := d.coerceColumn(header, cols)
+	if err != nil {
+		return err
+	}
 
 	d.insertHeader(index, header)
 
@@ -300,27 +366,7 @@ func (d *Dataset) insertHeader(index int, header string) {
 // ValidFailFast returns whether the Dataset is valid regarding constraints that have
 // been previously set on columns.
 func (d *Dataset) ValidFailFast() bool {
-	valid := true
-	for column, constraint := range d.constraints {
-		if constraint != nil {
-			for row, val := range d.Column(d.headers[column]) {
-				cellIsValid := true
-
-				switch val.(type) {
-				case DynamicColumn:
-					cellIsValid = constraint((val.(DynamicColumn))(d.data[row]))
-				default:
-					cellIsValid = constraint(val)
-				}
-
-				if !cellIsValid {
-					valid = false
-					break
-				}
-			}
-		}
-	}
-
+	valid := len(d.validate(true)) == 0
 	if valid {
 		d.ValidationErrors = make([]ValidationError, 0)
 	}
@@ -331,44 +377,106 @@ func (d *Dataset) ValidFailFast() bool {
 // Valid returns whether the Dataset is valid regarding constraints that have
 // been previously set on columns.
 // Its behaviour is different of ValidFailFast in a sense that it will validate the whole
-// Dataset and all the validation errors will be available by using Dataset.ValidationErrors
+// Dataset and all the validation errors will be available by using Dataset.ValidationErrors,
+// ordered by row then column.
 func (d *Dataset) Valid() bool {
-	d.ValidationErrors = make([]ValidationError, 0)
-
-	valid := true
-	for column, constraint := range d.constraints {
-		if constraint != nil {
-			for row, val := range d.Column(d.headers[column]) {
-				cellIsValid := true
-
-				switch val.(type) {
-				case DynamicColumn:
-					cellIsValid = constraint((val.(DynamicColumn))(d.data[row]))
-				default:
-					cellIsValid = constraint(val)
+	d.ValidationErrors = d.validate(false)
+	return len(d.ValidationErrors) == 0
+}
+
+// validate checks the constraints set on the columns and returns the validation
+// errors, stopping at the first one if failFast is set.
+func (d *Dataset) validate(failFast bool) []ValidationError {
+	errors := make([]ValidationError, 0)
+	rows := make([][]interface{}, len(d.data))
+	for i, r := range d.data {
+		rows[i] = evaluatedRow(r)
+	}
+
+	for j, header := range d.headers {
+		constraints := d.constraintsAt(j)
+		if len(constraints) == 0 {
+			continue
+		}
+		values := make([]interface{}, len(rows))
+		for i, r := range rows {
+			values[i] = r[j]
+		}
+		for _, c := range constraints {
+			for i, ok := range c.validate(values) {
+				if !ok {
+					errors = append(errors, ValidationError{i, j, header, []string{header},
+						c.Name, c.Message, values[i]})
+					if failFast {
+						return errors
+					}
 				}
+			}
+		}
+	}
 
-				if !cellIsValid {
-					d.ValidationErrors = append(d.ValidationErrors,
-						ValidationError{Row: row, Column: column})
-					valid = false
+	for _, c := range d.rowConstraints {
+		for i, r := range rows {
+			if e, ok := d.checkRowConstraint(c, i, r); !ok {
+				errors = append(errors, e)
+				if failFast {
+					return errors
 				}
 			}
 		}
 	}
-	return valid
+
+	if keyErrors := d.keyErrors(rows); len(keyErrors) > 0 {
+		if failFast {
+			return keyErrors[:1]
+		}
+		errors = append(errors, keyErrors...)
+	}
+
+	sortValidationErrors(errors)
+	return errors
+}
+
+// sortValidationErrors orders validation errors by row, then column, the errors
+// not related to a single column being last.
+func sortValidationErrors(errors []ValidationError) {
+	sort.SliceStable(errors, func(a, b int) bool {
+		if errors[a].Row != errors[b].Row {
+			return errors[a].Row < errors[b].Row
+		}
+		return uint(errors[a].Column) < uint(errors[b].Column)
+	})
+}
+
+// rowIsValid returns whether a row validates the constraints set on the columns.
+func (d *Dataset) rowIsValid(row []interface{}) bool {
+	row = evaluatedRow(row)
+	for j := range d.headers {
+		for _, c := range d.constraintsAt(j) {
+			if !c.validate(row[j : j+1])[0] {
+				return false
+			}
+		}
+	}
+	for _, c := range d.rowConstraints {
+		if _, ok := d.checkRowConstraint(c, -1, row); !ok {
+			return false
+		}
+	}
+	return true
 }
 
 // HasAnyConstraint returns whether the Dataset has any constraint set.
 func (d *Dataset) HasAnyConstraint() bool {
-	hasConstraint := false
-	for _, constraint := range d.constraints {
-		if constraint != nil {
-			hasConstraint = true
-			break
+	if len(d.rowConstraints) > 0 || d.hasKeys() {
+		return true
+	}
+	for j := range d.headers {
+		if len(d.constraintsAt(j)) > 0 {
+			return true
 		}
 	}
-	return hasConstraint
+	return false
 }
 
 // ValidSubset return a new Dataset containing only the rows validating their
@@ -394,50 +502,25 @@ func (d *Dataset) internalValidSubset(valid bool) *Dataset {
 		return d
 	}
 
-	nd := NewDataset(d.headers)
-	nd.data = make([][]interface{}, 0)
-	ndRowIndex := 0
-	nd.tags = make([][]string, 0)
+	invalidRows := make(map[int]bool)
+	for _, e := range d.validate(false) {
+		invalidRows[e.Row] = true
+	}
 
+	nd := NewDataset(d.headers)
 	for i, row := range d.data {
-		keep := true
-		for j, val := range d.data[i] {
-			if d.constraints[j] != nil {
-				switch val.(type) {
-				case DynamicColumn:
-					if valid {
-						keep = d.constraints[j]((val.(DynamicColumn))(row))
-					} else {
-						keep = !d.constraints[j]((val.(DynamicColumn))(row))
-					}
-				default:
-					if valid {
-						keep = d.constraints[j](val)
-					} else {
-						keep = !d.constraints[j](val)
-					}
-				}
-			}
-			if valid && !keep {
-				break
-			}
-		}
-		if keep {
-			nd.data = append(nd.data, make([]interface{}, 0, nd.cols))
-			nd.data[ndRowIndex] = append(nd.data[ndRowIndex], row...)
-
-			nd.tags = append(nd.tags, make([]string, 0, nd.cols))
-			nd.tags[ndRowIndex] = append(nd.tags[ndRowIndex], d.tags[i]...)
-			ndRowIndex++
+		if invalidRows[i] != valid {
+			r := make([]interface{}, 0, nd.cols)
+			nd.AppendTagged(append(r, row...), append(make([]string, 0), d.tags[i]...)...)
 		}
 	}
-	nd.cols = d.cols
-	nd.rows = ndRowIndex
 
 	return nd
 }
 
 // Stack stacks two Dataset by joining at the row level, and return new combined Dataset.
+// The returned Dataset has the Schema of this Dataset, if any, the rows of the
+// other Dataset being coerced to it.
 func (d *Dataset) Stack(other *Dataset) (*Dataset, error) {
 	if d.Width() != other.Width() {
 		return nil, ErrInvalidDimensions
@@ -446,6 +529,12 @@ func (d *Dataset) Stack(other *Dataset) (*Dataset, error) {
 	nd := NewDataset(d.headers)
 	nd.cols = d.cols
 	nd.rows = d.rows + other.rows
+	if d.fields != nil {
+		nd.fields = make(map[string]Field, len(d.fields))
+		for h, f := range d.fields {
+			nd.fields[h] = f
+		}
+	}
 
 	nd.tags = make([][]string, 0, nd.rows)
 	nd.tags = append(nd.tags, d.tags...)
@@ -453,7 +542,13 @@ func (d *Dataset) Stack(other *Dataset) (*Dataset, error) {
 
 	nd.data = make([][]interface{}, 0, nd.rows)
 	nd.data = append(nd.data, d.data...)
-	nd.data = append(nd.data, other.data...)
+	for _, r := range other.data {
+		row, err := nd.coerceRow(nd.fields, r)
+		if err != nil {
+			return nil, err
+		}
+		nd.data = append(nd.data, row)
+	}
 
 	return nd, nil
 }
@@ -572,12 +667,14 @@ func (d *Dataset) Slice(lower, upperNonInclusive int) (*Dataset, error) {
 
 // Filter filters a Dataset, returning a fresh Dataset including only the rows
 // previously tagged with one of the given tags. Returns a new Dataset.
+// Each row is included at most once, whatever the number of matching tags.
 func (d *Dataset) Filter(tags ...string) *Dataset {
 	nd := NewDataset(d.headers)
 	for rowIndex, rowValue := range d.data {
 		for _, filterTag := range tags {
 			if isTagged(filterTag, d.tags[rowIndex]) {
 				nd.AppendTagged(rowValue, d.tags[rowIndex]...) // copy tags
+				break
 			}
 		}
 	}
@@ -585,52 +682,23 @@ func (d *Dataset) Filter(tags ...string) *Dataset {
 }
 
 // Sort sorts the Dataset by a specific column. Returns a new Dataset.
+// Values are ordered as defined by Compare, nil values last.
 func (d *Dataset) Sort(column string) *Dataset {
 	return d.internalSort(column, false)
 }
 
 // SortReverse sorts the Dataset by a specific column in reverse order. Returns a new Dataset.
+// Values are ordered as defined by Compare, nil values last.
 func (d *Dataset) SortReverse(column string) *Dataset {
 	return d.internalSort(column, true)
 }
 
 func (d *Dataset) internalSort(column string, reverse bool) *Dataset {
-	nd := NewDataset(d.headers)
-	pairs := make([]entryPair, 0, nd.rows)
-	for i, v := range d.Column(column) {
-		pairs = append(pairs, entryPair{i, v})
-	}
-
-	var how sort.Interface
-	// sort by column
-	switch pairs[0].value.(type) {
-	case string:
-		how = byStringValue(pairs)
-	case int:
-		how = byIntValue(pairs)
-	case int64:
-		how = byInt64Value(pairs)
-	case uint64:
-		how = byUint64Value(pairs)
-	case float64:
-		how = byFloatValue(pairs)
-	case time.Time:
-		how = byTimeValue(pairs)
-	default:
-		// nothing
-	}
-
-	if !reverse {
-		sort.Sort(how)
-	} else {
-		sort.Sort(sort.Reverse(how))
-	}
-
-	// now iterate on the pairs and add the data sorted to the new Dataset
-	for _, p := range pairs {
-		nd.AppendTagged(d.data[p.index], d.tags[p.index]...)
+	nd, err := d.SortBy(SortKey{Column: column, Descending: reverse})
+	if err != nil {
+		// unknown column, nothing to sort on
+		nd, _ = d.Slice(0, d.rows)
 	}
-
 	return nd
 }
 
@@ -665,6 +733,7 @@ func (d *Dataset) DeleteRow(row int) error {
 		return ErrInvalidRowIndex
 	}
 	d.data = append(d.data[:row], d.data[row+1:]...)
+	d.tags = append(d.tags[:row], d.tags[row+1:]...)
 	d.rows--
 	return nil
 }
@@ -677,6 +746,10 @@ func (d *Dataset) DeleteColumn(header string) error {
 	}
 	d.cols--
 	d.headers = append(d.headers[:colIndex], d.headers[colIndex+1:]...)
+	d.constraints = append(d.constraints[:colIndex], d.constraints[colIndex+1:]...)
+	delete(d.namedConstraints, header)
+	delete(d.fields, header)
+	d.dropKeysOn(header)
 	// remove the column
 	for i := range d.data {
 		d.data[i] = append(d.data[i][:colIndex], d.data[i][colIndex+1:]...)
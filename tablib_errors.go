@@ -17,4 +17,23 @@ var (
 	ErrInvalidDataset = errors.New("tablib: Invalid dataset")
 	// ErrInvalidTag is returned when trying to add a tag which is not a string.
 	ErrInvalidTag = errors.New("tablib: A tag must be a string")
+	// ErrInvalidConversion is returned when a value cannot be converted to the
+	// type expected for its column.
+	ErrInvalidConversion = errors.New("tablib: Invalid value conversion")
+	// ErrUnquotableField is returned when exporting to CSV without quoting
+	// a field which contains special characters.
+	ErrUnquotableField = errors.New("tablib: Field needs to be quoted")
+	// ErrInvalidSheet is returned when trying to access a sheet which does not exist.
+	ErrInvalidSheet = errors.New("tablib: Invalid sheet")
+	// ErrInvalidTagQuery is returned when a boolean expression on tags cannot be parsed.
+	ErrInvalidTagQuery = errors.New("tablib: Invalid tag query")
+	// ErrNullValue is returned when a nil value is given to a column that is not nullable.
+	ErrNullValue = errors.New("tablib: Null value in a non nullable column")
+	// ErrNoJoinKey is returned when trying to join two Datasets without key columns.
+	ErrNoJoinKey = errors.New("tablib: No join key")
+	// ErrDuplicateHeader is returned when an operation would produce a Dataset
+	// having the same header twice.
+	ErrDuplicateHeader = errors.New("tablib: Duplicate header")
+	// ErrInvalidBatchSize is returned when trying to read a batch of less than one row.
+	ErrInvalidBatchSize = errors.New("tablib: Invalid batch size")
 )
@@ -0,0 +1,243 @@
+package tablib
+
+import "math"
+
+// AggregateFunc computes a single value from the values of a column in a group.
+type AggregateFunc func([]interface{}) interface{}
+
+// Aggregation represents an aggregation of a column, producing a column
+// named Header in the aggregated Dataset.
+type Aggregation struct {
+	Column string
+	Header string
+	Fn     AggregateFunc
+}
+
+// As returns a copy of the aggregation producing a column with a specific header.
+func (a Aggregation) As(header string) Aggregation {
+	a.Header = header
+	return a
+}
+
+// Aggregate returns an aggregation of a column using a custom function.
+func Aggregate(column, header string, fn AggregateFunc) Aggregation {
+	return Aggregation{column, header, fn}
+}
+
+// Count returns an aggregation counting the non nil values of a column.
+func Count(column string) Aggregation {
+	return Aggregation{column, "count(" + column + ")", aggregateCount}
+}
+
+// Sum returns an aggregation summing the numeric values of a column, as an int64
+// if they are all integers and their sum does not overflow, as a float64 otherwise.
+func Sum(column string) Aggregation {
+	return Aggregation{column, "sum(" + column + ")", aggregateSum}
+}
+
+// Mean returns an aggregation computing the mean of the numeric values of a column
+// as a float64, nil if there is none.
+func Mean(column string) Aggregation {
+	return Aggregation{column, "mean(" + column + ")", aggregateMean}
+}
+
+// Min returns an aggregation computing the minimum of the non nil values of a column,
+// as ordered by Compare.
+func Min(column string) Aggregation {
+	return Aggregation{column, "min(" + column + ")", aggregateMin}
+}
+
+// Max returns an aggregation computing the maximum of the non nil values of a column,
+// as ordered by Compare.
+func Max(column string) Aggregation {
+	return Aggregation{column, "max(" + column + ")", aggregateMax}
+}
+
+// First returns an aggregation keeping the first value of a column.
+func First(column string) Aggregation {
+	return Aggregation{column, "first(" + column + ")", aggregateFirst}
+}
+
+// Last returns an aggregation keeping the last value of a column.
+func Last(column string) Aggregation {
+	return Aggregation{column, "last(" + column + ")", aggregateLast}
+}
+
+// DistinctCount returns an aggregation counting the distinct non nil values of a column.
+func DistinctCount(column string) Aggregation {
+	return Aggregation{column, "distinct(" + column + ")", aggregateDistinctCount}
+}
+
+// GroupedDataset represents the rows of a Dataset grouped by the values of one
+// or more columns.
+type GroupedDataset struct {
+	dataset *Dataset
+	columns []int
+	// rows holds the evaluated rows of each group, in order of first appearance
+	rows [][][]interface{}
+}
+
+// GroupBy groups the rows of the Dataset by the values of one or more columns.
+// Numbers are grouped by value whatever their type, and nil values form a group.
+// Returns ErrInvalidColumnIndex if a column does not exist.
+func (d *Dataset) GroupBy(columns ...string) (*GroupedDataset, error) {
+	indexes, err := columnIndexes(d, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GroupedDataset{d, indexes, make([][][]interface{}, 0)}
+	groups := make(map[string]int)
+	for _, r := range d.data {
+		row := evaluatedRow(r)
+		values := make([]interface{}, len(indexes))
+		for k, j := range indexes {
+			values[k] = row[j]
+		}
+		key := hashKey(values...)
+		i, ok := groups[key]
+		if !ok {
+			i = len(g.rows)
+			groups[key] = i
+			g.rows = append(g.rows, nil)
+		}
+		g.rows[i] = append(g.rows[i], row)
+	}
+	return g, nil
+}
+
+// Len returns the number of groups.
+func (g *GroupedDataset) Len() int {
+	return len(g.rows)
+}
+
+// Aggregate computes the aggregations for each group, returning a new Dataset
+// with one row per group, in order of first appearance. Its columns are the
+// grouping columns followed by one column per aggregation.
+// Returns ErrInvalidColumnIndex if an aggregated column does not exist.
+func (g *GroupedDataset) Aggregate(aggregations ...Aggregation) (*Dataset, error) {
+	headers := make([]string, 0, len(g.columns)+len(aggregations))
+	for _, j := range g.columns {
+		headers = append(headers, g.dataset.headers[j])
+	}
+	indexes := make([]int, 0, len(aggregations))
+	for _, a := range aggregations {
+		j := indexOfColumn(a.Column, g.dataset)
+		if j == -1 {
+			return nil, ErrInvalidColumnIndex
+		}
+		indexes = append(indexes, j)
+		headers = append(headers, a.Header)
+	}
+
+	nd := NewDataset(headers)
+	for _, rows := range g.rows {
+		row := make([]interface{}, 0, len(headers))
+		for _, j := range g.columns {
+			row = append(row, rows[0][j])
+		}
+		for k, a := range aggregations {
+			values := make([]interface{}, len(rows))
+			for i, r := range rows {
+				values[i] = r[indexes[k]]
+			}
+			row = append(row, a.Fn(values))
+		}
+		nd.Append(row)
+	}
+	return nd, nil
+}
+
+func aggregateCount(values []interface{}) interface{} {
+	count := 0
+	for _, v := range values {
+		if v != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func aggregateSum(values []interface{}) interface{} {
+	var intSum int64
+	var floatSum float64
+	allInts := true
+	for _, v := range values {
+		if i, ok := toInt64(v); ok {
+			if (i > 0 && intSum > math.MaxInt64-i) || (i < 0 && intSum < math.MinInt64-i) {
+				allInts = false
+			}
+			intSum += i
+			floatSum += float64(i)
+		} else if f, ok := toFloat(v); ok {
+			floatSum += f
+			allInts = false
+		}
+	}
+	if allInts {
+		return intSum
+	}
+	return floatSum
+}
+
+func aggregateMean(values []interface{}) interface{} {
+	var sum float64
+	count := 0
+	for _, v := range values {
+		if f, ok := toFloat(v); ok {
+			sum += f
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return sum / float64(count)
+}
+
+func aggregateMin(values []interface{}) interface{} {
+	return aggregateExtremum(values, -1)
+}
+
+func aggregateMax(values []interface{}) interface{} {
+	return aggregateExtremum(values, 1)
+}
+
+// aggregateExtremum returns the non nil value v for which Compare(v, other)
+// equals sign for every other value.
+func aggregateExtremum(values []interface{}, sign int) interface{} {
+	var extremum interface{}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if extremum == nil || Compare(v, extremum) == sign {
+			extremum = v
+		}
+	}
+	return extremum
+}
+
+func aggregateFirst(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func aggregateLast(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[len(values)-1]
+}
+
+func aggregateDistinctCount(values []interface{}) interface{} {
+	distinct := make(map[string]bool)
+	for _, v := range values {
+		if v != nil {
+			distinct[hashKey(v)] = true
+		}
+	}
+	return len(distinct)
+}
@@ -0,0 +1,234 @@
+package tablib
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType represents the Go type of the values of a column.
+type ColumnType int
+
+const (
+	// ColumnTypeString is used for columns holding string values.
+	ColumnTypeString ColumnType = iota
+	// ColumnTypeInt is used for columns holding int64 values.
+	ColumnTypeInt
+	// ColumnTypeFloat is used for columns holding float64 values.
+	ColumnTypeFloat
+	// ColumnTypeBool is used for columns holding bool values.
+	ColumnTypeBool
+	// ColumnTypeTime is used for columns holding time.Time values.
+	ColumnTypeTime
+)
+
+var (
+	columnTypeNames = map[ColumnType]string{ColumnTypeString: "string",
+		ColumnTypeInt: "int64", ColumnTypeFloat: "float64",
+		ColumnTypeBool: "bool", ColumnTypeTime: "time.Time"}
+	// inferenceOrder is the order in which types are tried while inferring,
+	// from the narrowest to the widest.
+	inferenceOrder = []ColumnType{ColumnTypeInt, ColumnTypeFloat,
+		ColumnTypeBool, ColumnTypeTime}
+	// timeLayouts are the layouts recognized while inferring time.Time values.
+	timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05",
+		"2006-01-02 15:04:05", "2006-01-02"}
+)
+
+// String returns the name of the Go type of the ColumnType.
+func (t ColumnType) String() string {
+	return columnTypeNames[t]
+}
+
+// InferOptions holds the options used when inferring the types of the columns
+// of a Dataset.
+type InferOptions struct {
+	// Overrides forces the type of some columns, by header, instead of inferring it.
+	Overrides map[string]ColumnType
+}
+
+// ColumnInference reports the type that has been given to a column.
+type ColumnInference struct {
+	Header     string
+	Type       ColumnType
+	Overridden bool
+}
+
+// LoadCSVWithInference loads a Dataset by its CSV representation and converts
+// each column to the narrowest type consistent with all its values.
+func LoadCSVWithInference(input []byte, opts InferOptions) (*Dataset, []ColumnInference, error) {
+	ds, err := LoadCSV(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := ds.InferTypes(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ds, report, nil
+}
+
+// LoadTSVWithInference loads a Dataset by its TSV representation and converts
+// each column to the narrowest type consistent with all its values.
+func LoadTSVWithInference(input []byte, opts InferOptions) (*Dataset, []ColumnInference, error) {
+	ds, err := LoadTSV(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := ds.InferTypes(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ds, report, nil
+}
+
+// InferTypes scans each column of the Dataset holding string values and converts
+// it to the narrowest consistent type, trying in order int64, float64, bool
+// and time.Time (RFC3339 or ISO dates), falling back to string.
+// Empty cells are ignored while inferring and become nil in converted columns.
+// Numbers having leading zeros, such as zip codes, keep their column a string,
+// and so do NaN and infinities.
+// Columns holding non string values, such as dynamic columns, are left untouched
+// and are not part of the returned report.
+// Returns ErrInvalidConversion if a value cannot be converted to an overridden type,
+// or if an overridden column does not hold only strings, in which case the Dataset
+// is left unchanged.
+func (d *Dataset) InferTypes(opts InferOptions) ([]ColumnInference, error) {
+	report := make([]ColumnInference, 0, d.cols)
+	converted := make(map[int][]interface{})
+	for j, h := range d.headers {
+		t, overridden := opts.Overrides[h]
+		values, ok := d.stringColumn(j)
+		if !ok {
+			if overridden {
+				return nil, ErrInvalidConversion
+			}
+			continue
+		}
+
+		if !overridden {
+			t = inferColumnType(values)
+		}
+
+		if t != ColumnTypeString {
+			column := make([]interface{}, len(values))
+			for i, v := range values {
+				c, err := convertString(v, t)
+				if err != nil {
+					return nil, err
+				}
+				column[i] = c
+			}
+			converted[j] = column
+		}
+		report = append(report, ColumnInference{h, t, overridden})
+	}
+
+	// only update the Dataset once every column is converted
+	for j, column := range converted {
+		for i, v := range column {
+			d.data[i][j] = v
+		}
+	}
+	return report, nil
+}
+
+// stringColumn returns the values of the column at the given index if they are
+// all strings.
+func (d *Dataset) stringColumn(index int) ([]string, bool) {
+	values := make([]string, 0, d.rows)
+	for _, row := range d.data {
+		s, ok := row[index].(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, s)
+	}
+	return values, true
+}
+
+// inferColumnType returns the narrowest type all the non empty values can be
+// converted to.
+func inferColumnType(values []string) ColumnType {
+	nonEmpty := 0
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			nonEmpty++
+		}
+	}
+	if nonEmpty == 0 {
+		return ColumnTypeString
+	}
+
+	for _, t := range inferenceOrder {
+		fits := true
+		for _, v := range values {
+			c, err := convertString(v, t)
+			if err != nil || !inferable(v, c) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return t
+		}
+	}
+	return ColumnTypeString
+}
+
+// inferable returns whether a value converted from a string may be inferred:
+// numbers must be finite and not written with leading zeros.
+func inferable(value string, converted interface{}) bool {
+	switch n := converted.(type) {
+	case int64:
+		return !hasLeadingZero(value)
+	case float64:
+		return !math.IsNaN(n) && !math.IsInf(n, 0) && !hasLeadingZero(value)
+	}
+	return true
+}
+
+// hasLeadingZero returns whether a number is written with a leading zero
+// followed by another digit, such as 007 or -01.5.
+func hasLeadingZero(value string) bool {
+	v := strings.TrimLeft(strings.TrimSpace(value), "+-")
+	return len(v) > 1 && v[0] == '0' && v[1] >= '0' && v[1] <= '9'
+}
+
+// convertString converts a string to a given type.
+// An empty string is converted to nil unless the type is ColumnTypeString.
+func convertString(value string, t ColumnType) (interface{}, error) {
+	if t == ColumnTypeString {
+		return value, nil
+	}
+
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return nil, nil
+	}
+
+	switch t {
+	case ColumnTypeInt:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+	case ColumnTypeFloat:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	case ColumnTypeBool:
+		if strings.EqualFold(v, "true") {
+			return true, nil
+		} else if strings.EqualFold(v, "false") {
+			return false, nil
+		}
+	case ColumnTypeTime:
+		for _, layout := range timeLayouts {
+			if tm, err := time.Parse(layout, v); err == nil {
+				return tm, nil
+			}
+		}
+	}
+	return nil, ErrInvalidConversion
+}
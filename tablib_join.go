@@ -0,0 +1,177 @@
+package tablib
+
+// JoinKind represents the kind of a join between two Datasets.
+type JoinKind int
+
+const (
+	// InnerJoin keeps only the rows having a match in both Datasets.
+	InnerJoin JoinKind = iota
+	// LeftJoin keeps all the rows of the left Dataset.
+	LeftJoin
+	// RightJoin keeps all the rows of the right Dataset.
+	RightJoin
+	// FullJoin keeps all the rows of both Datasets.
+	FullJoin
+)
+
+// Join joins the Dataset with another one on one or more key columns, which
+// must exist in both Datasets. Returns a new Dataset.
+// Columns of the other Dataset whose header clashes with a header of this
+// Dataset are suffixed with "_right".
+// See JoinWithSuffixes for details.
+func (d *Dataset) Join(other *Dataset, on []string, kind JoinKind) (*Dataset, error) {
+	return d.JoinWithSuffixes(other, on, kind, "", "_right")
+}
+
+// JoinWithSuffixes joins the Dataset with another one on one or more key columns,
+// which must exist in both Datasets. Returns a new Dataset.
+// The headers of the returned Dataset are the headers of this Dataset followed by
+// the non key headers of the other one. Clashing headers are suffixed with
+// leftSuffix or rightSuffix depending on the Dataset they come from.
+// Rows are matched using a hash join, keys being equal if their values are
+// equal, numbers being compared by value whatever their type. nil keys never match.
+// Rows come in the order of this Dataset, followed, for right and full joins, by
+// the unmatched rows of the other Dataset. Cells without a matching row are nil.
+// Tags are kept from this Dataset, dynamic columns are evaluated and constraints are lost.
+// Returns ErrInvalidColumnIndex if a key column does not exist, ErrNoJoinKey
+// if no key column is given, or ErrDuplicateHeader if the returned Dataset would
+// have the same header twice, such as a suffixed header clashing with another one.
+func (d *Dataset) JoinWithSuffixes(other *Dataset, on []string, kind JoinKind,
+	leftSuffix, rightSuffix string) (*Dataset, error) {
+	if len(on) == 0 {
+		return nil, ErrNoJoinKey
+	}
+	leftKeys, err := columnIndexes(d, on)
+	if err != nil {
+		return nil, err
+	}
+	rightKeys, err := columnIndexes(other, on)
+	if err != nil {
+		return nil, err
+	}
+
+	// the non key columns of the right Dataset
+	isRightKey := make(map[int]bool)
+	for _, j := range rightKeys {
+		isRightKey[j] = true
+	}
+	rightColumns := make([]int, 0, other.cols)
+	for j := range other.headers {
+		if !isRightKey[j] {
+			rightColumns = append(rightColumns, j)
+		}
+	}
+
+	// headers, suffixing the clashing ones
+	rightHeaders := make(map[string]bool)
+	for _, j := range rightColumns {
+		rightHeaders[other.headers[j]] = true
+	}
+	headers := make([]string, 0, d.cols+len(rightColumns))
+	leftHeaders := make(map[string]bool)
+	for _, h := range d.headers {
+		leftHeaders[h] = true
+		if rightHeaders[h] {
+			h += leftSuffix
+		}
+		headers = append(headers, h)
+	}
+	for _, j := range rightColumns {
+		h := other.headers[j]
+		if leftHeaders[h] {
+			h += rightSuffix
+		}
+		headers = append(headers, h)
+	}
+	usedHeaders := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		if usedHeaders[h] {
+			return nil, ErrDuplicateHeader
+		}
+		usedHeaders[h] = true
+	}
+
+	// hash the right Dataset
+	rightRows := make([][]interface{}, other.rows)
+	index := make(map[string][]int)
+	for i, r := range other.data {
+		rightRows[i] = evaluatedRow(r)
+		if key, ok := joinKey(rightRows[i], rightKeys); ok {
+			index[key] = append(index[key], i)
+		}
+	}
+
+	nd := NewDataset(headers)
+	matched := make([]bool, other.rows)
+	for i, r := range d.data {
+		left := evaluatedRow(r)
+		var matches []int
+		if key, ok := joinKey(left, leftKeys); ok {
+			matches = index[key]
+		}
+		for _, m := range matches {
+			matched[m] = true
+			nd.AppendTagged(joinRow(left, rightRows[m], rightColumns), d.tags[i]...)
+		}
+		if len(matches) == 0 && (kind == LeftJoin || kind == FullJoin) {
+			nd.AppendTagged(joinRow(left, nil, rightColumns), d.tags[i]...)
+		}
+	}
+
+	if kind == RightJoin || kind == FullJoin {
+		for i, right := range rightRows {
+			if matched[i] {
+				continue
+			}
+			left := make([]interface{}, d.cols)
+			for k, j := range leftKeys {
+				left[j] = right[rightKeys[k]]
+			}
+			nd.Append(joinRow(left, right, rightColumns))
+		}
+	}
+
+	return nd, nil
+}
+
+// columnIndexes returns the indexes of the given columns.
+// Returns ErrInvalidColumnIndex if a column does not exist.
+func columnIndexes(d *Dataset, headers []string) ([]int, error) {
+	indexes := make([]int, 0, len(headers))
+	for _, h := range headers {
+		j := indexOfColumn(h, d)
+		if j == -1 {
+			return nil, ErrInvalidColumnIndex
+		}
+		indexes = append(indexes, j)
+	}
+	return indexes, nil
+}
+
+// joinKey returns the hash key of the key columns of a row, and false if one
+// of the key values is nil.
+func joinKey(row []interface{}, keys []int) (string, bool) {
+	values := make([]interface{}, len(keys))
+	for k, j := range keys {
+		if row[j] == nil {
+			return "", false
+		}
+		values[k] = row[j]
+	}
+	return hashKey(values...), true
+}
+
+// joinRow builds a joined row from a left row and the given columns of a
+// right row, which may be nil.
+func joinRow(left, right []interface{}, rightColumns []int) []interface{} {
+	row := make([]interface{}, 0, len(left)+len(rightColumns))
+	row = append(row, left...)
+	for _, j := range rightColumns {
+		if right == nil {
+			row = append(row, nil)
+		} else {
+			row = append(row, right[j])
+		}
+	}
+	return row
+}
@@ -1,39 +1,51 @@
 package tablib
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 )
 
-// LoadJSON loads a dataset from a YAML source.
+// LoadJSON loads a dataset from a JSON source.
 func LoadJSON(jsonContent []byte) (*Dataset, error) {
-	var input []map[string]interface{}
-	if err := json.Unmarshal(jsonContent, &input); err != nil {
+	return LoadJSONWithOptions(jsonContent, LoadOptions{})
+}
+
+// LoadJSONWithOptions loads a dataset from a JSON source using specific options.
+// Columns are ordered as their keys first appear in the source.
+func LoadJSONWithOptions(jsonContent []byte, opts LoadOptions) (*Dataset, error) {
+	input, err := decodeJSONRecords(jsonContent)
+	if err != nil {
 		return nil, err
 	}
 
-	return internalLoadFromDict(input)
+	return internalLoadFromDict(input, opts)
 }
 
 // LoadDatabookJSON loads a Databook from a JSON source.
 func LoadDatabookJSON(jsonContent []byte) (*Databook, error) {
-	var input []map[string]interface{}
-	var internalInput []map[string]interface{}
+	return LoadDatabookJSONWithOptions(jsonContent, LoadOptions{})
+}
+
+// LoadDatabookJSONWithOptions loads a Databook from a JSON source using specific options.
+func LoadDatabookJSONWithOptions(jsonContent []byte, opts LoadOptions) (*Databook, error) {
+	var input []struct {
+		Title string          `json:"title"`
+		Data  json.RawMessage `json:"data"`
+	}
 	if err := json.Unmarshal(jsonContent, &input); err != nil {
 		return nil, err
 	}
 
 	db := NewDatabook()
 	for _, d := range input {
-		b, err := json.Marshal(d["data"])
+		records, err := decodeJSONRecords(d.Data)
 		if err != nil {
 			return nil, err
 		}
-		if err := json.Unmarshal(b, &internalInput); err != nil {
-			return nil, err
-		}
 
-		if ds, err := internalLoadFromDict(internalInput); err == nil {
-			db.AddSheet(d["title"].(string), ds)
+		if ds, err := internalLoadFromDict(records, opts); err == nil {
+			db.AddSheet(d.Title, ds)
 		} else {
 			return nil, err
 		}
@@ -42,6 +54,60 @@ func LoadDatabookJSON(jsonContent []byte) (*Databook, error) {
 	return db, nil
 }
 
+// decodeJSONRecords decodes a JSON array of objects, keeping the order
+// of the keys of each object.
+func decodeJSONRecords(jsonContent []byte) ([]*orderedRecord, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonContent))
+	if err := expectJSONDelim(dec, '['); err != nil {
+		return nil, err
+	}
+
+	records := make([]*orderedRecord, 0)
+	for dec.More() {
+		if err := expectJSONDelim(dec, '{'); err != nil {
+			return nil, err
+		}
+		r := newOrderedRecord()
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := t.(string)
+			if !ok {
+				return nil, fmt.Errorf("tablib: unexpected JSON token %v", t)
+			}
+			var value interface{}
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			r.set(key, value)
+		}
+		if err := expectJSONDelim(dec, '}'); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	if err := expectJSONDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// expectJSONDelim reads the next token of the decoder and checks that it is
+// the given delimiter.
+func expectJSONDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("tablib: expected JSON delimiter %v, got %v", delim, t)
+	}
+	return nil
+}
+
 // JSON returns a JSON representation of the Dataset as an Exportable.
 func (d *Dataset) JSON() (*Exportable, error) {
 	back := d.Dict()
@@ -53,6 +119,74 @@ func (d *Dataset) JSON() (*Exportable, error) {
 	return newExportableFromBytes(b), nil
 }
 
+// JSONSchema returns the JSON Schema describing the JSON representation of the
+// Dataset as an Exportable. The columns described by the Schema of the Dataset
+// have a type, the other ones accepting any value.
+func (d *Dataset) JSONSchema() (*Exportable, error) {
+	b := newBuffer()
+	b.WriteString(`{"$schema":"http://json-schema.org/draft-07/schema#","type":"array",` +
+		`"items":{"type":"object","properties":{`)
+	for i, h := range d.headers {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		name, err := json.Marshal(h)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteString(":")
+
+		property := make(map[string]interface{})
+		if f, ok := d.fields[h]; ok {
+			t, format := jsonSchemaType(f.Type)
+			if f.Nullable && f.Default == nil {
+				property["type"] = []string{t, "null"}
+			} else {
+				property["type"] = t
+			}
+			if format != "" {
+				property["format"] = format
+			}
+			if f.Description != "" {
+				property["description"] = f.Description
+			}
+			if f.Default != nil {
+				property["default"] = f.Default
+			}
+		}
+		p, err := json.Marshal(property)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(p)
+	}
+	b.WriteString(`},"required":`)
+	required, err := json.Marshal(d.headers)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(required)
+	b.WriteString("}}")
+
+	return newExportable(b), nil
+}
+
+// jsonSchemaType returns the JSON Schema type and format of a ColumnType.
+func jsonSchemaType(t ColumnType) (string, string) {
+	switch t {
+	case ColumnTypeInt:
+		return "integer", ""
+	case ColumnTypeFloat:
+		return "number", ""
+	case ColumnTypeBool:
+		return "boolean", ""
+	case ColumnTypeTime:
+		return "string", "date-time"
+	}
+	return "string", ""
+}
+
 // JSON returns a JSON representation of the Databook as an Exportable.
 func (d *Databook) JSON() (*Exportable, error) {
 	b := newBuffer()
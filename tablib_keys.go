@@ -0,0 +1,228 @@
+package tablib
+
+import "strings"
+
+// ForeignKey represents a reference from some columns of a Dataset to the
+// columns of another table, being the title of a sheet within a Databook.
+type ForeignKey struct {
+	// Columns are the headers of the referencing columns.
+	Columns []string
+	// Table is the referenced table.
+	Table string
+	// References are the headers of the referenced columns.
+	References []string
+}
+
+// datasetKeys holds the keys declared on a Dataset.
+type datasetKeys struct {
+	primary []string
+	unique  [][]string
+	foreign []ForeignKey
+}
+
+// SetPrimaryKey declares the columns forming the primary key of the Dataset.
+// Valid reports the rows having a nil or duplicated primary key.
+// Returns ErrInvalidColumnIndex if a column does not exist.
+func (d *Dataset) SetPrimaryKey(columns ...string) error {
+	if _, err := columnIndexes(d, columns); err != nil {
+		return err
+	}
+	d.keys.primary = columns
+	return nil
+}
+
+// PrimaryKey returns the columns forming the primary key of the Dataset, if any.
+func (d *Dataset) PrimaryKey() []string {
+	return d.keys.primary
+}
+
+// AddUniqueKey declares columns whose combined values must be unique.
+// Valid reports the rows having a duplicated key, keys having nil values being ignored.
+// Returns ErrInvalidColumnIndex if a column does not exist.
+func (d *Dataset) AddUniqueKey(columns ...string) error {
+	if _, err := columnIndexes(d, columns); err != nil {
+		return err
+	}
+	d.keys.unique = append(d.keys.unique, columns)
+	return nil
+}
+
+// UniqueKeys returns the unique keys of the Dataset.
+func (d *Dataset) UniqueKeys() [][]string {
+	return d.keys.unique
+}
+
+// AddForeignKey declares that the values of some columns reference the values
+// of the columns of another table, checked by Databook.Valid when the table is
+// a sheet of the Databook. Keys having nil values are ignored.
+// Returns ErrInvalidColumnIndex if a column does not exist, or ErrInvalidDimensions
+// if the number of columns and references differ.
+func (d *Dataset) AddForeignKey(columns []string, table string, references []string) error {
+	if _, err := columnIndexes(d, columns); err != nil {
+		return err
+	}
+	if len(columns) != len(references) {
+		return ErrInvalidDimensions
+	}
+	d.keys.foreign = append(d.keys.foreign, ForeignKey{columns, table, references})
+	return nil
+}
+
+// ForeignKeys returns the foreign keys of the Dataset.
+func (d *Dataset) ForeignKeys() []ForeignKey {
+	return d.keys.foreign
+}
+
+// hasKeys returns whether a primary or unique key is declared on the Dataset.
+func (d *Dataset) hasKeys() bool {
+	return len(d.keys.primary) > 0 || len(d.keys.unique) > 0
+}
+
+// keyErrors returns the validation errors of the primary and unique keys
+// of the evaluated rows.
+func (d *Dataset) keyErrors(rows [][]interface{}) []ValidationError {
+	errors := make([]ValidationError, 0)
+	if len(d.keys.primary) > 0 {
+		errors = append(errors, d.duplicateKeyErrors(rows, d.keys.primary, "primary_key")...)
+	}
+	for _, key := range d.keys.unique {
+		errors = append(errors, d.duplicateKeyErrors(rows, key, "unique_key")...)
+	}
+	return errors
+}
+
+// duplicateKeyErrors returns the validation errors of the rows whose key is
+// already used by a previous row, or is nil for a primary key.
+func (d *Dataset) duplicateKeyErrors(rows [][]interface{}, key []string, name string) []ValidationError {
+	errors := make([]ValidationError, 0)
+	indexes, _ := columnIndexes(d, key)
+	seen := make(map[string]bool)
+	for i, r := range rows {
+		values, hasNil := keyValues(r, indexes)
+		switch {
+		case hasNil && name == "primary_key":
+			errors = append(errors, ValidationError{i, -1, "", key, name,
+				"must not be null", keyRow(key, values)})
+		case hasNil:
+			// nil values are never duplicates
+		case seen[hashKey(values...)]:
+			errors = append(errors, ValidationError{i, -1, "", key, name,
+				"must be unique", keyRow(key, values)})
+		default:
+			seen[hashKey(values...)] = true
+		}
+	}
+	return errors
+}
+
+// Valid returns whether all the Datasets of the Databook are valid regarding
+// their constraints and keys, and whether their foreign keys referencing other
+// sheets of the Databook have no orphan. The validation errors are available in
+// the ValidationErrors of each Dataset. Foreign keys referencing tables which are
+// not sheets of the Databook are ignored, while foreign keys whose columns do not
+// exist are reported by a ValidationError whose Row is -1.
+func (d *Databook) Valid() bool {
+	valid := true
+	for _, s := range d.sheets {
+		ds := s.dataset
+		if !ds.Valid() {
+			valid = false
+		}
+		for _, fk := range ds.keys.foreign {
+			errors := d.orphanErrors(ds, fk)
+			if len(errors) > 0 {
+				ds.ValidationErrors = append(ds.ValidationErrors, errors...)
+				sortValidationErrors(ds.ValidationErrors)
+				valid = false
+			}
+		}
+	}
+	return valid
+}
+
+// orphanErrors returns the validation errors of the rows of a Dataset referencing
+// rows that do not exist in the sheet referenced by a foreign key, if any.
+func (d *Databook) orphanErrors(ds *Dataset, fk ForeignKey) []ValidationError {
+	errors := make([]ValidationError, 0)
+	i := d.indexOfSheet(fk.Table)
+	if i == -1 {
+		return errors
+	}
+	ref := d.sheets[i].dataset
+	refIndexes, refErr := columnIndexes(ref, fk.References)
+	indexes, err := columnIndexes(ds, fk.Columns)
+	if refErr != nil || err != nil {
+		return append(errors, ValidationError{-1, -1, "", fk.Columns, "foreign_key",
+			"must reference existing columns " + fk.Table + "(" + strings.Join(fk.References, ", ") + ")", nil})
+	}
+
+	referenced := make(map[string]bool)
+	for _, r := range ref.data {
+		values, _ := keyValues(evaluatedRow(r), refIndexes)
+		referenced[hashKey(values...)] = true
+	}
+
+	message := "must reference an existing " + fk.Table + "(" + strings.Join(fk.References, ", ") + ")"
+	for i, r := range ds.data {
+		values, hasNil := keyValues(evaluatedRow(r), indexes)
+		if !hasNil && !referenced[hashKey(values...)] {
+			errors = append(errors, ValidationError{i, -1, "", fk.Columns, "foreign_key",
+				message, keyRow(fk.Columns, values)})
+		}
+	}
+	return errors
+}
+
+// keyValues returns the values of a row at some indexes, and whether one of them is nil.
+func keyValues(row []interface{}, indexes []int) ([]interface{}, bool) {
+	values := make([]interface{}, len(indexes))
+	hasNil := false
+	for k, j := range indexes {
+		values[k] = row[j]
+		hasNil = hasNil || row[j] == nil
+	}
+	return values, hasNil
+}
+
+// keyRow returns the values of a key by header.
+func keyRow(key []string, values []interface{}) Row {
+	row := make(Row, len(key))
+	for k, h := range key {
+		row[h] = values[k]
+	}
+	return row
+}
+
+// clone returns a copy of the keys not sharing any slice with them.
+func (k datasetKeys) clone() datasetKeys {
+	c := datasetKeys{primary: append([]string(nil), k.primary...)}
+	for _, key := range k.unique {
+		c.unique = append(c.unique, append([]string(nil), key...))
+	}
+	for _, fk := range k.foreign {
+		c.foreign = append(c.foreign, ForeignKey{append([]string(nil), fk.Columns...), fk.Table,
+			append([]string(nil), fk.References...)})
+	}
+	return c
+}
+
+// dropKeysOn removes the keys involving a column.
+func (d *Dataset) dropKeysOn(header string) {
+	if containsString(header, d.keys.primary) {
+		d.keys.primary = nil
+	}
+	unique := make([][]string, 0, len(d.keys.unique))
+	for _, key := range d.keys.unique {
+		if !containsString(header, key) {
+			unique = append(unique, key)
+		}
+	}
+	d.keys.unique = unique
+	foreign := make([]ForeignKey, 0, len(d.keys.foreign))
+	for _, fk := range d.keys.foreign {
+		if !containsString(header, fk.Columns) {
+			foreign = append(foreign, fk)
+		}
+	}
+	d.keys.foreign = foreign
+}
@@ -0,0 +1,133 @@
+package tablib
+
+// Pivot spreads a long Dataset into a wide one, returning a new Dataset.
+// Its first column holds the distinct values of the index column, followed by
+// one column per distinct value of the columns column, in order of first
+// appearance. Each cell is the aggregation by aggFn of the values of the values
+// column sharing the same index and column values, nil if there are none.
+// If aggFn is nil, the first value is kept, with its type.
+// Each row carries the tags of all the rows it has been built from.
+// Returns ErrInvalidColumnIndex if a column does not exist, or ErrDuplicateHeader
+// if two distinct values of the columns column, such as 2015 and "2015", have the
+// same string representation, or one of them is the index header.
+func (d *Dataset) Pivot(index, columns, values string, aggFn AggregateFunc) (*Dataset, error) {
+	indexes, err := columnIndexes(d, []string{index, columns, values})
+	if err != nil {
+		return nil, err
+	}
+	if aggFn == nil {
+		aggFn = aggregateFirst
+	}
+
+	headers := []string{index}
+	usedHeaders := map[string]bool{index: true}
+	columnPositions := make(map[string]int)
+	rowPositions := make(map[string]int)
+	indexValues := make([]interface{}, 0)
+	tags := make([][]string, 0)
+	cells := make([][][]interface{}, 0)
+	for i, r := range d.data {
+		row := evaluatedRow(r)
+		columnKey := hashKey(row[indexes[1]])
+		c, ok := columnPositions[columnKey]
+		if !ok {
+			c = len(headers) - 1
+			columnPositions[columnKey] = c
+			header := d.asString(row[indexes[1]])
+			if usedHeaders[header] {
+				return nil, ErrDuplicateHeader
+			}
+			usedHeaders[header] = true
+			headers = append(headers, header)
+			for k := range cells {
+				cells[k] = append(cells[k], nil)
+			}
+		}
+
+		rowKey := hashKey(row[indexes[0]])
+		p, ok := rowPositions[rowKey]
+		if !ok {
+			p = len(indexValues)
+			rowPositions[rowKey] = p
+			indexValues = append(indexValues, row[indexes[0]])
+			tags = append(tags, make([]string, 0))
+			cells = append(cells, make([][]interface{}, len(headers)-1))
+		}
+		cells[p][c] = append(cells[p][c], row[indexes[2]])
+		for _, tag := range d.tags[i] {
+			if !isTagged(tag, tags[p]) {
+				tags[p] = append(tags[p], tag)
+			}
+		}
+	}
+
+	nd := NewDataset(headers)
+	for p, v := range indexValues {
+		row := make([]interface{}, 0, len(headers))
+		row = append(row, v)
+		for _, c := range cells[p] {
+			if len(c) == 0 {
+				row = append(row, nil)
+			} else {
+				row = append(row, aggFn(c))
+			}
+		}
+		nd.AppendTagged(row, tags[p]...)
+	}
+	return nd, nil
+}
+
+// Melt unpivots a wide Dataset into a long one, returning a new Dataset.
+// Its columns are the id columns followed by a "variable" column holding the
+// header of a value column and a "value" column holding its value. Each row of
+// the Dataset produces one row per value column, keeping its tags.
+// If valueColumns is empty, all the columns which are not id columns are used.
+// Returns ErrInvalidColumnIndex if a column does not exist, or ErrDuplicateHeader
+// if an id column is given twice or is named "variable" or "value".
+func (d *Dataset) Melt(idColumns, valueColumns []string) (*Dataset, error) {
+	ids, err := columnIndexes(d, idColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(valueColumns) == 0 {
+		isID := make(map[int]bool)
+		for _, j := range ids {
+			isID[j] = true
+		}
+		for j, h := range d.headers {
+			if !isID[j] {
+				valueColumns = append(valueColumns, h)
+			}
+		}
+	}
+	vals, err := columnIndexes(d, valueColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	if containsString("variable", idColumns) || containsString("value", idColumns) {
+		return nil, ErrDuplicateHeader
+	}
+	for k, h := range idColumns {
+		if containsString(h, idColumns[:k]) {
+			return nil, ErrDuplicateHeader
+		}
+	}
+	headers := make([]string, 0, len(ids)+2)
+	headers = append(headers, idColumns...)
+	headers = append(headers, "variable", "value")
+
+	nd := NewDataset(headers)
+	for i, r := range d.data {
+		row := evaluatedRow(r)
+		for k, j := range vals {
+			melted := make([]interface{}, 0, len(headers))
+			for _, id := range ids {
+				melted = append(melted, row[id])
+			}
+			melted = append(melted, valueColumns[k], row[j])
+			nd.AppendTagged(melted, d.tags[i]...)
+		}
+	}
+	return nd, nil
+}
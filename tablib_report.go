@@ -0,0 +1,169 @@
+package tablib
+
+import (
+	"html"
+	"strings"
+)
+
+// ValidationReport holds the outcome of the validation of a Dataset, to be
+// exported for humans.
+type ValidationReport struct {
+	// Errors are the validation errors, ordered by row then column.
+	Errors  []ValidationError
+	dataset *Dataset
+}
+
+// Validate validates the whole Dataset like Valid does and returns a ValidationReport.
+func (d *Dataset) Validate() *ValidationReport {
+	d.Valid()
+	return &ValidationReport{d.ValidationErrors, d}
+}
+
+// Valid returns whether the validated Dataset is valid.
+func (r *ValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Dataset returns the errors of the report as a Dataset having the columns row,
+// column, value, rule and message, rows being numbered from 0.
+// For row constraints, the participating columns are separated by commas and
+// the value lists the participating values.
+func (r *ValidationReport) Dataset() *Dataset {
+	ds := NewDataset([]string{"row", "column", "value", "rule", "message"})
+	for _, e := range r.Errors {
+		ds.AppendValues(e.Row, r.columnOf(e), r.valueOf(e), e.Constraint, e.Message)
+	}
+	return ds
+}
+
+// CSV returns a CSV representation of the errors of the report as an Exportable.
+func (r *ValidationReport) CSV() (*Exportable, error) {
+	return r.Dataset().CSV()
+}
+
+// JSON returns a JSON representation of the errors of the report as an Exportable.
+func (r *ValidationReport) JSON() (*Exportable, error) {
+	return r.Dataset().JSON()
+}
+
+// HTML returns a HTML representation of the errors of the report as an Exportable,
+// the offending values and messages being escaped.
+func (r *ValidationReport) HTML() *Exportable {
+	records := r.Dataset().Records()
+	escaped := NewDataset(records[0])
+	for _, record := range records[1:] {
+		row := make([]interface{}, len(record))
+		for j, v := range record {
+			row[j] = html.EscapeString(v)
+		}
+		escaped.Append(row)
+	}
+	return escaped.HTML()
+}
+
+// Markdown returns a Markdown representation of the errors of the report as an Exportable.
+func (r *ValidationReport) Markdown() *Exportable {
+	return r.Dataset().Markdown()
+}
+
+// Summary returns the number of errors per column and rule as a Dataset having
+// the columns column, rule and count, in order of first appearance.
+func (r *ValidationReport) Summary() *Dataset {
+	type group struct{ column, rule string }
+	groups := make([]group, 0)
+	counts := make(map[group]int)
+	for _, e := range r.Errors {
+		g := group{r.columnOf(e), e.Constraint}
+		if _, ok := counts[g]; !ok {
+			groups = append(groups, g)
+		}
+		counts[g]++
+	}
+
+	ds := NewDataset([]string{"column", "rule", "count"})
+	for _, g := range groups {
+		ds.AppendValues(g.column, g.rule, counts[g])
+	}
+	return ds
+}
+
+// CountsByColumn returns the number of errors per column, the failures of row
+// constraints counting for each participating column.
+func (r *ValidationReport) CountsByColumn() map[string]int {
+	counts := make(map[string]int)
+	for _, e := range r.Errors {
+		for _, h := range e.Columns {
+			counts[h]++
+		}
+	}
+	return counts
+}
+
+// CountsByRule returns the number of errors per constraint name.
+func (r *ValidationReport) CountsByRule() map[string]int {
+	counts := make(map[string]int)
+	for _, e := range r.Errors {
+		counts[e.Constraint]++
+	}
+	return counts
+}
+
+// HighlightHTML returns the HTML representation of the validated Dataset as an
+// Exportable, where the failing cells have the class "invalid" and the messages
+// of the failed constraints as title.
+func (r *ValidationReport) HighlightHTML() *Exportable {
+	d := r.dataset
+	failures := make(map[int]map[string][]string)
+	for _, e := range r.Errors {
+		if failures[e.Row] == nil {
+			failures[e.Row] = make(map[string][]string)
+		}
+		for _, h := range e.Columns {
+			failures[e.Row][h] = append(failures[e.Row][h], e.Message+" ("+e.Constraint+")")
+		}
+	}
+
+	b := newBuffer()
+	b.WriteString("<table class=\"table table-striped\">\n\t<thead>\n\t\t<tr>")
+	for _, h := range d.headers {
+		b.WriteString("\n\t\t\t<th>" + html.EscapeString(h) + "</th>")
+	}
+	b.WriteString("\n\t\t</tr>\n\t</thead>\n\t<tbody>")
+	for i, row := range d.Records()[1:] {
+		b.WriteString("\n\t\t<tr>")
+		for j, v := range row {
+			if messages, ok := failures[i][d.headers[j]]; ok {
+				b.WriteString("\n\t\t\t<td class=\"invalid\" title=\"" +
+					html.EscapeString(strings.Join(messages, "; ")) + "\">")
+			} else {
+				b.WriteString("\n\t\t\t<td>")
+			}
+			b.WriteString(html.EscapeString(v) + "</td>")
+		}
+		b.WriteString("\n\t\t</tr>")
+	}
+	b.WriteString("\n\t</tbody>\n</table>")
+
+	return newExportable(b)
+}
+
+// columnOf returns the column of a ValidationError as shown in the report.
+func (r *ValidationReport) columnOf(e ValidationError) string {
+	if e.Column == -1 {
+		return strings.Join(e.Columns, ", ")
+	}
+	return e.Header
+}
+
+// valueOf returns the value of a ValidationError as shown in the report.
+func (r *ValidationReport) valueOf(e ValidationError) interface{} {
+	row, ok := e.Value.(Row)
+	if e.Column != -1 || !ok {
+		return e.Value
+	}
+	values := make([]string, len(e.Columns))
+	for i, h := range e.Columns {
+		values[i] = h + "=" + r.dataset.asString(row[h])
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,192 @@
+package tablib
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Field describes a column of a Dataset.
+// Fields are not nullable unless Nullable is set: nil values are then rejected
+// with ErrNullValue unless a Default is given, and SQL exports declare the
+// column NOT NULL.
+type Field struct {
+	// Name is the header of the column.
+	Name string
+	// Type is the Go type of the values of the column.
+	Type ColumnType
+	// Nullable allows nil values in the column, false by default.
+	Nullable bool
+	// Default, if not nil, replaces the nil values.
+	Default interface{}
+	// Description describes the column, used by JSONSchema.
+	Description string
+	// Format is the number format of the column, see SetColumnFormat.
+	Format string
+}
+
+// Schema describes the columns of a Dataset.
+type Schema []Field
+
+// NewDatasetWithSchema creates a new Dataset whose headers and schema are given by a Schema.
+func NewDatasetWithSchema(schema Schema) *Dataset {
+	headers := make([]string, len(schema))
+	for i, f := range schema {
+		headers[i] = f.Name
+	}
+	d := NewDataset(headers)
+	d.SetSchema(schema)
+	return d
+}
+
+// SetSchema attaches a Schema to the Dataset, describing all or part of its columns.
+// The values already in the Dataset and the ones appended or inserted afterwards are
+// coerced to the type of their column: strings are parsed, integers are converted to
+// int64 and numbers to float64, any value being accepted by string columns.
+// nil values are replaced by the default value of their column, if any.
+// Returns ErrInvalidColumnIndex if a field does not match a column, ErrInvalidConversion
+// if a value cannot be coerced, or ErrNullValue if a value of a non nullable column
+// is nil, in which case the Dataset is left unchanged.
+func (d *Dataset) SetSchema(schema Schema) error {
+	fields := make(map[string]Field, len(schema))
+	for _, f := range schema {
+		if indexOfColumn(f.Name, d) == -1 {
+			return ErrInvalidColumnIndex
+		}
+		fields[f.Name] = f
+	}
+
+	data := make([][]interface{}, len(d.data))
+	for i, r := range d.data {
+		row, err := d.coerceRow(fields, r)
+		if err != nil {
+			return err
+		}
+		data[i] = row
+	}
+
+	d.data = data
+	d.fields = fields
+	for _, f := range schema {
+		if f.Format != "" {
+			d.SetColumnFormat(f.Name, f.Format)
+		}
+	}
+	return nil
+}
+
+// Schema returns the fields of the Schema attached to the Dataset, in the order
+// of the columns. Returns nil if no Schema is attached.
+func (d *Dataset) Schema() Schema {
+	if d.fields == nil {
+		return nil
+	}
+	schema := make(Schema, 0, len(d.fields))
+	for _, h := range d.headers {
+		if f, ok := d.fields[h]; ok {
+			schema = append(schema, f)
+		}
+	}
+	return schema
+}
+
+// Field returns the field of the Schema describing a column, and whether it exists.
+func (d *Dataset) Field(header string) (Field, bool) {
+	f, ok := d.fields[header]
+	return f, ok
+}
+
+// coerceRow returns a copy of a row whose values are coerced to the fields of
+// their column, or the row itself if there is no field.
+func (d *Dataset) coerceRow(fields map[string]Field, row []interface{}) ([]interface{}, error) {
+	if len(fields) == 0 {
+		return row, nil
+	}
+
+	coerced := make([]interface{}, len(row))
+	for j, v := range row {
+		f, ok := fields[d.headers[j]]
+		if !ok {
+			coerced[j] = v
+			continue
+		}
+		c, err := f.coerce(v)
+		if err != nil {
+			return nil, err
+		}
+		coerced[j] = c
+	}
+	return coerced, nil
+}
+
+// coerceColumn returns a copy of the values of a column coerced to the field
+// named after its header, or the values themselves if there is no such field.
+func (d *Dataset) coerceColumn(header string, values []interface{}) ([]interface{}, error) {
+	f, ok := d.fields[header]
+	if !ok {
+		return values, nil
+	}
+	coerced := make([]interface{}, len(values))
+	for i, v := range values {
+		c, err := f.coerce(v)
+		if err != nil {
+			return nil, err
+		}
+		coerced[i] = c
+	}
+	return coerced, nil
+}
+
+// coerce converts a value to the type of the field.
+func (f Field) coerce(v interface{}) (interface{}, error) {
+	if _, ok := v.(DynamicColumn); ok {
+		return v, nil
+	}
+	if s, ok := v.(string); ok && f.Type != ColumnTypeString {
+		converted, err := convertString(s, f.Type)
+		if err != nil {
+			return nil, err
+		}
+		v = converted
+	}
+	if v == nil {
+		if f.Default == nil {
+			if f.Nullable {
+				return nil, nil
+			}
+			return nil, ErrNullValue
+		}
+		v = f.Default
+	}
+
+	switch f.Type {
+	case ColumnTypeString:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case time.Time:
+			return s.Format(time.RFC3339), nil
+		}
+		return fmt.Sprint(v), nil
+	case ColumnTypeInt:
+		if i, ok := toInt64(v); ok {
+			return i, nil
+		}
+		if fl, ok := toFloat(v); ok && fl == math.Trunc(fl) && math.Abs(fl) < math.MaxInt64 {
+			return int64(fl), nil
+		}
+	case ColumnTypeFloat:
+		if fl, ok := toFloat(v); ok {
+			return fl, nil
+		}
+	case ColumnTypeBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ColumnTypeTime:
+		if t, ok := v.(time.Time); ok {
+			return t, nil
+		}
+	}
+	return nil, ErrInvalidConversion
+}
@@ -0,0 +1,141 @@
+package tablib
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// sniffDelimiters are the delimiters recognized by SniffCSV, by order of preference.
+var sniffDelimiters = []rune{',', ';', '\t', '|', ':'}
+
+// SniffCSV guesses the CSV dialect of a sample, usually the first kilobytes
+// of a file. It detects the byte order mark, the line terminator, '#' comment
+// lines, the delimiter among ',', ';', '\t', '|' and ':', leading spaces,
+// lazy quotes, and whether the first row is a header row.
+func SniffCSV(sample []byte) CSVOptions {
+	var opts CSVOptions
+	if bytes.HasPrefix(sample, utf8BOM) {
+		opts.WriteBOM = true
+		sample = sample[len(utf8BOM):]
+	}
+	opts.UseCRLF = bytes.Contains(sample, []byte("\r\n"))
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.Replace(string(sample), "\r\n", "\n", -1), "\n") {
+		if strings.HasPrefix(line, "#") {
+			opts.Comment = '#'
+		} else if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	// the last line may have been truncated
+	if len(lines) > 2 && !bytes.HasSuffix(sample, []byte("\n")) {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return opts
+	}
+
+	opts.Delimiter = sniffDelimiter(lines)
+	opts.TrimLeadingSpace = sniffLeadingSpace(lines, opts.Delimiter)
+
+	content := []byte(strings.Join(lines, "\n"))
+	records, err := opts.newReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		opts.LazyQuotes = true
+		records, err = opts.newReader(bytes.NewReader(content)).ReadAll()
+		if err != nil {
+			return opts
+		}
+	}
+	opts.NoHeader = !sniffHeader(records)
+
+	return opts
+}
+
+// sniffDelimiter returns the delimiter appearing the same number of times
+// on each line, preferring the one appearing the most.
+func sniffDelimiter(lines []string) rune {
+	best, bestCount := ',', 0
+	for _, d := range sniffDelimiters {
+		count := countOutsideQuotes(lines[0], d)
+		if count == 0 || count <= bestCount {
+			continue
+		}
+		consistent := true
+		for _, line := range lines[1:] {
+			if countOutsideQuotes(line, d) != count {
+				consistent = false
+				break
+			}
+		}
+		if consistent {
+			best, bestCount = d, count
+		}
+	}
+	return best
+}
+
+// countOutsideQuotes counts the occurrences of a rune outside quoted parts of a line.
+func countOutsideQuotes(line string, r rune) int {
+	count := 0
+	quoted := false
+	for _, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == r && !quoted {
+			count++
+		}
+	}
+	return count
+}
+
+// sniffLeadingSpace returns whether every field following a delimiter starts with a space.
+func sniffLeadingSpace(lines []string, delimiter rune) bool {
+	found := false
+	for _, line := range lines {
+		for _, field := range strings.Split(line, string(delimiter))[1:] {
+			if !strings.HasPrefix(field, " ") {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// sniffHeader returns whether the first record looks like a header row: a
+// column whose values are numeric except in the first record votes for a
+// header, a column numeric everywhere votes against it.
+// Without any vote, the first record is considered a header row.
+func sniffHeader(records [][]string) bool {
+	if len(records) < 2 {
+		return true
+	}
+
+	score := 0
+	for j := range records[0] {
+		numeric := true
+		for _, r := range records[1:] {
+			if j >= len(r) || !isNumeric(r[j]) {
+				numeric = false
+				break
+			}
+		}
+		if numeric {
+			if isNumeric(records[0][j]) {
+				score--
+			} else {
+				score++
+			}
+		}
+	}
+	return score >= 0
+}
+
+// isNumeric returns whether a string represents a number.
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
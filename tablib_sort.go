@@ -1,48 +1,334 @@
 package tablib
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
 
-// entryPair represents a pair of a value and its row index in the dataset
-// which is used while sorting the dataset using a colum.
-type entryPair struct {
-	index int
-	value interface{}
+// SortKey represents a column to sort a Dataset by.
+type SortKey struct {
+	// Column is the header of the column.
+	Column string
+	// Descending sorts in descending order instead of ascending order.
+	Descending bool
+	// NullsFirst puts nil values first instead of last, whatever the order.
+	NullsFirst bool
+	// Natural compares strings in natural order, that is case insensitively and
+	// comparing the runs of ASCII digits they contain by value, so that
+	// "file2" < "File10". Other characters are compared by code point, locale
+	// aware collation is out of scope.
+	Natural bool
 }
 
-type byIntValue []entryPair
+// SortBy sorts the Dataset by one or more columns, each key being used to
+// order the rows which are equal regarding the previous keys.
+// The sort is stable: rows which are equal regarding all the keys keep their
+// original order. Returns a new Dataset, tags are conserved.
+// Returns ErrInvalidColumnIndex if a column does not exist.
+func (d *Dataset) SortBy(keys ...SortKey) (*Dataset, error) {
+	columns := make([]string, 0, len(keys))
+	for _, k := range keys {
+		columns = append(columns, k.Column)
+	}
+	indexes, err := columnIndexes(d, columns)
+	if err != nil {
+		return nil, err
+	}
 
-func (p byIntValue) Len() int           { return len(p) }
-func (p byIntValue) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byIntValue) Less(i, j int) bool { return p[i].value.(int) < p[j].value.(int) }
+	rows := make([][]interface{}, d.rows)
+	for i, r := range d.data {
+		rows[i] = evaluatedRow(r)
+	}
 
-type byInt64Value []entryPair
+	return d.sortRows(func(a, b int) bool {
+		for k, key := range keys {
+			if c := compareSortKey(rows[a][indexes[k]], rows[b][indexes[k]], key); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}), nil
+}
+
+// SortFunc sorts the Dataset using a custom function reporting whether the row a
+// must come before the row b. The sort is stable.
+// Returns a new Dataset, tags are conserved.
+func (d *Dataset) SortFunc(less func(a, b map[string]interface{}) bool) *Dataset {
+	rows := make([]map[string]interface{}, d.rows)
+	for i := range d.data {
+		rows[i], _ = d.Row(i)
+	}
+
+	return d.sortRows(func(a, b int) bool {
+		return less(rows[a], rows[b])
+	})
+}
+
+// sortRows returns a new Dataset holding the rows sorted using a function
+// comparing two row indexes.
+func (d *Dataset) sortRows(less func(a, b int) bool) *Dataset {
+	order := make([]int, d.rows)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(order[i], order[j])
+	})
+
+	nd := NewDataset(d.headers)
+	for _, i := range order {
+		nd.AppendTagged(d.data[i], d.tags[i]...)
+	}
+	return nd
+}
+
+// compareSortKey compares two values regarding a sort key.
+func compareSortKey(a, b interface{}, key SortKey) int {
+	if a == nil || b == nil {
+		if a == b {
+			return 0
+		}
+		if (a == nil) == key.NullsFirst {
+			return -1
+		}
+		return 1
+	}
+
+	var c int
+	sa, aIsString := a.(string)
+	sb, bIsString := b.(string)
+	if key.Natural && aIsString && bIsString {
+		c = compareNatural(sa, sb)
+	} else {
+		c = Compare(a, b)
+	}
+	if key.Descending {
+		return -c
+	}
+	return c
+}
+
+// compareNatural compares two strings in natural order: case insensitively,
+// the sequences of ASCII digits being compared by numeric value.
+func compareNatural(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isASCIIDigit(ra[i]) && isASCIIDigit(rb[j]) {
+			si := i
+			for i < len(ra) && isASCIIDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && isASCIIDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return compareInts(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return compareInts(int(ca), int(cb))
+		}
+		i++
+		j++
+	}
+	if c := compareInts(len(ra)-i, len(rb)-j); c != 0 {
+		return c
+	}
+	// equal in natural order, fall back to lexical order to be deterministic
+	return strings.Compare(a, b)
+}
 
-func (p byInt64Value) Len() int           { return len(p) }
-func (p byInt64Value) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byInt64Value) Less(i, j int) bool { return p[i].value.(int64) < p[j].value.(int64) }
+// isASCIIDigit returns whether a rune is one of 0-9.
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
 
-type byUint64Value []entryPair
+// kinds of values, by order in the total ordering defined by Compare
+const (
+	kindNil = iota
+	kindBool
+	kindNumber
+	kindString
+	kindTime
+	kindOther
+)
 
-func (p byUint64Value) Len() int           { return len(p) }
-func (p byUint64Value) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byUint64Value) Less(i, j int) bool { return p[i].value.(uint64) < p[j].value.(uint64) }
+// Compare compares two values as stored in a Dataset, returning -1, 0 or +1
+// whether a is lower than, equal to or greater than b.
+// It defines a total ordering over all values, used by sorting, filtering
+// and aggregations, so that heterogeneous columns never cause a panic.
+// Values of different kinds are ordered by kind:
+//
+//	nil < bool < numbers < string < time.Time < any other type
+//
+// Values of the same kind are ordered as follows:
+//   - false < true
+//   - numbers of any int, uint or float width by value whatever their type,
+//     compared exactly, even between integers and floats, NaN being lower
+//     than any other number
+//   - strings lexically, byte per byte
+//   - time.Time chronologically
+//   - other values by type name, then by their fmt representation
+//
+// Compare does not evaluate dynamic columns, which need their row: sorting,
+// filtering and aggregations evaluate them before comparing their values,
+// while a DynamicColumn given to Compare is ordered as any other type.
+func Compare(a, b interface{}) int {
+	ka, kb := kindOf(a), kindOf(b)
+	if ka != kb {
+		return compareInts(ka, kb)
+	}
 
-type byFloatValue []entryPair
+	switch ka {
+	case kindBool:
+		return compareInts(boolRank(a.(bool)), boolRank(b.(bool)))
+	case kindNumber:
+		return compareNumbers(a, b)
+	case kindString:
+		return strings.Compare(a.(string), b.(string))
+	case kindTime:
+		ta, tb := a.(time.Time), b.(time.Time)
+		if ta.Before(tb) {
+			return -1
+		} else if ta.After(tb) {
+			return 1
+		}
+		return 0
+	case kindOther:
+		if c := strings.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b)); c != 0 {
+			return c
+		}
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+	return 0 // both nil
+}
 
-func (p byFloatValue) Len() int           { return len(p) }
-func (p byFloatValue) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byFloatValue) Less(i, j int) bool { return p[i].value.(float64) < p[j].value.(float64) }
+// kindOf returns the kind of a value.
+func kindOf(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return kindNil
+	case bool:
+		return kindBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return kindNumber
+	case string:
+		return kindString
+	case time.Time:
+		return kindTime
+	}
+	return kindOther
+}
 
-type byTimeValue []entryPair
+// boolRank returns 0 for false and 1 for true.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
 
-func (p byTimeValue) Len() int      { return len(p) }
-func (p byTimeValue) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
-func (p byTimeValue) Less(i, j int) bool {
-	return p[i].value.(time.Time).UnixNano() < p[j].value.(time.Time).UnixNano()
+// compareNumbers compares two numbers of any type by value.
+func compareNumbers(a, b interface{}) int {
+	nega, maga, aIsInt := integerParts(a)
+	negb, magb, bIsInt := integerParts(b)
+	if aIsInt && bIsInt {
+		switch {
+		case nega != negb && nega:
+			return -1
+		case nega != negb:
+			return 1
+		case nega:
+			return compareUints(magb, maga)
+		}
+		return compareUints(maga, magb)
+	}
+
+	fa, _ := toFloat(a)
+	fb, _ := toFloat(b)
+	switch {
+	case math.IsNaN(fa) && math.IsNaN(fb):
+		return 0
+	case math.IsNaN(fa):
+		return -1
+	case math.IsNaN(fb):
+		return 1
+	case aIsInt && !math.IsInf(fb, 0):
+		// compare exactly, float64 cannot represent every integer above 2^53
+		return exactFloat(nega, maga).Cmp(big.NewFloat(fb))
+	case bIsInt && !math.IsInf(fa, 0):
+		return big.NewFloat(fa).Cmp(exactFloat(negb, magb))
+	}
+	return compareFloats(fa, fb)
 }
 
-type byStringValue []entryPair
+// exactFloat returns the exact value of an integer given by its sign and magnitude.
+func exactFloat(negative bool, magnitude uint64) *big.Float {
+	f := new(big.Float).SetUint64(magnitude)
+	if negative {
+		f.Neg(f)
+	}
+	return f
+}
 
-func (p byStringValue) Len() int           { return len(p) }
-func (p byStringValue) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byStringValue) Less(i, j int) bool { return p[i].value.(string) < p[j].value.(string) }
+// integerParts returns the sign and the magnitude of an integer, and false if
+// the value is not an integer.
+func integerParts(v interface{}) (bool, uint64, bool) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64:
+		i := reflect.ValueOf(v).Int()
+		if i < 0 {
+			return true, uint64(-(i + 1)) + 1, true
+		}
+		return false, uint64(i), true
+	case uint, uint8, uint16, uint32, uint64:
+		return false, reflect.ValueOf(v).Uint(), true
+	}
+	return false, 0, false
+}
+
+// compareFloats compares two float64.
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareUints compares two uint64.
+func compareUints(a, b uint64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareInts compares two int.
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -2,148 +2,739 @@ package tablib
 
 import (
 	"bytes"
-	"regexp"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// SQLDialect represents the SQL dialect of a database, used to quote identifiers
+// and literals and to choose the types of the columns.
+type SQLDialect int
+
+const (
+	// DialectMySQL is the dialect of MySQL and MariaDB.
+	DialectMySQL SQLDialect = iota
+	// DialectPostgres is the dialect of PostgreSQL.
+	DialectPostgres
+	// DialectSQLite is the dialect of SQLite.
+	DialectSQLite
+	// DialectSQLServer is the dialect of Microsoft SQL Server.
+	DialectSQLServer
+)
+
+// sqlKind represents the kind of values of a SQL column.
+type sqlKind int
+
+const (
+	sqlString sqlKind = iota
+	sqlNumeric
+	sqlInt
+	sqlBool
+	sqlTime
+)
+
 var (
-	typePostgres = "postgres"
-	typeMySQL    = "mysql"
-	defaults     = map[string]string{"various." + typePostgres: "TEXT",
-		"various." + typeMySQL: "VARCHAR(100)", "numeric." + typePostgres: "NUMERIC",
-		"numeric." + typeMySQL: "DOUBLE"}
+	// sqlTypes are the types of the columns by kind, for each dialect.
+	sqlTypes = map[SQLDialect]map[sqlKind]string{
+		DialectMySQL: {sqlNumeric: "DOUBLE", sqlInt: "BIGINT", sqlBool: "BOOLEAN",
+			sqlTime: "TIMESTAMP"},
+		DialectPostgres: {sqlString: "TEXT", sqlNumeric: "NUMERIC", sqlInt: "BIGINT",
+			sqlBool: "BOOLEAN", sqlTime: "TIMESTAMPTZ"},
+		DialectSQLite: {sqlString: "TEXT", sqlNumeric: "REAL", sqlInt: "INTEGER",
+			sqlBool: "INTEGER", sqlTime: "TEXT"},
+		DialectSQLServer: {sqlNumeric: "FLOAT", sqlInt: "BIGINT", sqlBool: "BIT",
+			sqlTime: "DATETIMEOFFSET"},
+	}
+	// sqlVariousTypes are the types of the columns holding values of various kinds.
+	sqlVariousTypes = map[SQLDialect]string{DialectMySQL: "VARCHAR(100)",
+		DialectPostgres: "TEXT", DialectSQLite: "TEXT", DialectSQLServer: "NVARCHAR(MAX)"}
+	// sqlIDColumns are the definitions of the synthetic id column.
+	sqlIDColumns = map[SQLDialect]string{DialectMySQL: "INT NOT NULL AUTO_INCREMENT PRIMARY KEY",
+		DialectPostgres: "SERIAL PRIMARY KEY", DialectSQLite: "INTEGER PRIMARY KEY",
+		DialectSQLServer: "INT IDENTITY(1,1) PRIMARY KEY"}
+	// schemaSQLKinds are the kinds of the columns described by a Schema.
+	schemaSQLKinds = map[ColumnType]sqlKind{ColumnTypeString: sqlString,
+		ColumnTypeInt: sqlInt, ColumnTypeFloat: sqlNumeric, ColumnTypeBool: sqlBool,
+		ColumnTypeTime: sqlTime}
+	mysqlEscaper = strings.NewReplacer(`\`, `\\`, "'", "''", "\x00", `\0`,
+		"\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+	copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
 )
 
+// SQLContent represents the statements produced by a SQL export.
+type SQLContent int
+
+const (
+	// SQLSchemaAndData produces both the CREATE TABLE statement and the data.
+	SQLSchemaAndData SQLContent = iota
+	// SQLSchemaOnly produces only the CREATE TABLE statement.
+	SQLSchemaOnly
+	// SQLDataOnly produces only the data, within a transaction.
+	SQLDataOnly
+)
+
+// SQLOptions holds the options used when exporting a Dataset as SQL.
+type SQLOptions struct {
+	// Dialect is the SQL dialect to use.
+	Dialect SQLDialect
+	// Content selects the statements to produce.
+	Content SQLContent
+	// BatchSize is the number of rows inserted by each INSERT statement, 1 if not set.
+	BatchSize int
+	// UpsertKeys, if set, are the columns identifying the rows to update instead
+	// of inserting them, using ON CONFLICT DO UPDATE with Postgres and SQLite,
+	// ON DUPLICATE KEY UPDATE with MySQL and MERGE with SQL Server. They must
+	// form a primary or unique key of the table. The keys which are not headers
+	// of the Dataset are ignored, plain INSERT statements being used if none is.
+	UpsertKeys []string
+	// Copy loads the data with a COPY FROM STDIN statement in text format when
+	// the dialect is Postgres, BatchSize and UpsertKeys being ignored. It is
+	// ignored with the other dialects, which use INSERT statements.
+	Copy bool
+}
+
+// QuoteIdentifier quotes a table or column name.
+func (s SQLDialect) QuoteIdentifier(name string) string {
+	switch s {
+	case DialectMySQL:
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	case DialectSQLServer:
+		return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// QuoteString quotes a string literal.
+func (s SQLDialect) QuoteString(value string) string {
+	switch s {
+	case DialectMySQL:
+		return "'" + mysqlEscaper.Replace(value) + "'"
+	case DialectSQLServer:
+		return "N'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// Placeholder returns the placeholder of the n-th bind argument of a prepared
+// statement, starting at 1.
+func (s SQLDialect) Placeholder(n int) string {
+	switch s {
+	case DialectPostgres:
+		return "$" + strconv.Itoa(n)
+	case DialectSQLServer:
+		return "@p" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// sqlColumn represents a column of a SQL table.
+type sqlColumn struct {
+	header  string
+	kind    sqlKind
+	sqlType string
+	values  []interface{}
+}
+
 // columnSQLType determines the type of a column
 // if throughout the whole column values have the same type then this type is
 // returned, otherwise the VARCHAR/TEXT type is returned.
 // numeric types are coerced into DOUBLE/NUMERIC
-func (d *Dataset) columnSQLType(header, dbType string) (string, []interface{}) {
-	types := 0
-	currentType := ""
-	maxString := 0
+// The type of the Schema of the column is used if any.
+func (d *Dataset) columnSQLType(header string, dialect SQLDialect) sqlColumn {
 	values := d.Column(header)
+	if f, ok := d.fields[header]; ok && f.Type != ColumnTypeString {
+		kind := schemaSQLKinds[f.Type]
+		return sqlColumn{header, kind, sqlTypes[dialect][kind], values}
+	}
+
+	kinds := 0
+	currentKind := sqlKind(-1)
+	maxString := 0
 	for _, c := range values {
+		kind := currentKind
 		switch c.(type) {
 		case uint, uint8, uint16, uint32, uint64,
 			int, int8, int16, int32, int64,
 			float32, float64:
-			if currentType != "numeric" {
-				currentType = "numeric"
-				types++
-			}
+			kind = sqlNumeric
+		case bool:
+			kind = sqlBool
 		case time.Time:
-			if currentType != "time" {
-				currentType = "time"
-				types++
-			}
+			kind = sqlTime
 		case string:
-			if currentType != "string" {
-				currentType = "string"
-				types++
-			}
+			kind = sqlString
 			if len(c.(string)) > maxString {
 				maxString = len(c.(string))
 			}
 		}
+		if kind != currentKind {
+			currentKind = kind
+			kinds++
+		}
 	}
 
-	if types > 1 {
-		return defaults["various."+dbType], values
+	if kinds > 1 {
+		return sqlColumn{header, sqlString, sqlVariousTypes[dialect], values}
 	}
-	switch currentType {
-	case "numeric":
-		return defaults["numeric."+dbType], values
-	case "time":
-		return "TIMESTAMP", values
-	default:
-		if dbType == typePostgres {
-			return "TEXT", values
+	switch currentKind {
+	case sqlNumeric, sqlBool, sqlTime:
+		return sqlColumn{header, currentKind, sqlTypes[dialect][currentKind], values}
+	}
+	sqlType := sqlTypes[dialect][sqlString]
+	switch dialect {
+	case DialectMySQL:
+		sqlType = "VARCHAR(" + strconv.Itoa(maxString) + ")"
+	case DialectSQLServer:
+		sqlType = "NVARCHAR(" + strconv.Itoa(maxString) + ")"
+		if maxString == 0 || maxString > 4000 {
+			sqlType = "NVARCHAR(MAX)"
 		}
-		return "VARCHAR(" + strconv.Itoa(maxString) + ")", values
 	}
-}
-
-// isStringColumn returns whether a column is VARCHAR/TEXT
-func isStringColumn(c string) bool {
-	return strings.HasPrefix(c, "VARCHAR") || strings.HasPrefix(c, "TEXT")
+	return sqlColumn{header, sqlString, sqlType, values}
 }
 
 // MySQL returns a string representing a suite of MySQL commands
 // recreating the Dataset into a table.
+// The primary, unique and foreign keys of the Dataset are declared on the table,
+// a synthetic id column being used as primary key if none is declared.
 func (d *Dataset) MySQL(table string) *Exportable {
-	return d.sql(table, typeMySQL)
+	return d.SQL(table, SQLOptions{Dialect: DialectMySQL})
 }
 
 // Postgres returns a string representing a suite of Postgres commands
 // recreating the Dataset into a table.
+// The primary, unique and foreign keys of the Dataset are declared on the table,
+// a synthetic id column being used as primary key if none is declared.
 func (d *Dataset) Postgres(table string) *Exportable {
-	return d.sql(table, typePostgres)
+	return d.SQL(table, SQLOptions{Dialect: DialectPostgres})
+}
+
+// SQLite returns a string representing a suite of SQLite commands
+// recreating the Dataset into a table.
+func (d *Dataset) SQLite(table string) *Exportable {
+	return d.SQL(table, SQLOptions{Dialect: DialectSQLite})
 }
 
-// sql returns a string representing a suite of SQL commands
+// SQLServer returns a string representing a suite of SQL Server commands
 // recreating the Dataset into a table.
-func (d *Dataset) sql(table, dbType string) *Exportable {
+func (d *Dataset) SQLServer(table string) *Exportable {
+	return d.SQL(table, SQLOptions{Dialect: DialectSQLServer})
+}
+
+// SQL returns a string representing a suite of SQL commands recreating the
+// Dataset into a table, using specific options. Identifiers and literals are
+// quoted according to the dialect, nil values being written as NULL.
+// The primary, unique and foreign keys of the Dataset are declared on the table,
+// a synthetic id column being used as primary key if none is declared. It is
+// named id, prefixed by underscores if the Dataset already has such a header.
+func (d *Dataset) SQL(table string, opts SQLOptions) *Exportable {
+	b := newBuffer()
+
+	tableSQL, columns := d.createTable(table, opts.Dialect, nil, nil)
+	if opts.Content != SQLDataOnly {
+		b.WriteString(tableSQL)
+	}
+	if opts.Content == SQLSchemaOnly {
+		return newExportable(b)
+	}
+
+	b.WriteString(sqlBegin(opts.Dialect) + "\n")
+	d.writeData(b, table, columns, opts)
+	b.WriteString("\nCOMMIT;\n")
+
+	return newExportable(b)
+}
+
+// MySQL returns a string representing a suite of MySQL commands recreating
+// the Databook, with a table per sheet, see SQL.
+func (d *Databook) MySQL() *Exportable {
+	return d.SQL(SQLOptions{Dialect: DialectMySQL})
+}
+
+// Postgres returns a string representing a suite of Postgres commands recreating
+// the Databook, with a table per sheet, see SQL.
+func (d *Databook) Postgres() *Exportable {
+	return d.SQL(SQLOptions{Dialect: DialectPostgres})
+}
+
+// SQLite returns a string representing a suite of SQLite commands recreating
+// the Databook, with a table per sheet, see SQL.
+func (d *Databook) SQLite() *Exportable {
+	return d.SQL(SQLOptions{Dialect: DialectSQLite})
+}
+
+// SQLServer returns a string representing a suite of SQL Server commands recreating
+// the Databook, with a table per sheet, see SQL.
+func (d *Databook) SQLServer() *Exportable {
+	return d.SQL(SQLOptions{Dialect: DialectSQLServer})
+}
+
+// SQL returns a string representing a suite of SQL commands recreating the
+// Databook within a single transaction, using specific options. Each sheet is
+// exported like Dataset.SQL does into a table named after its title, where the
+// characters other than ASCII letters, digits and underscores are replaced by
+// underscores. The tables are created, then filled, in the order of the sheets,
+// except that the sheets referenced by the foreign keys of other sheets come first.
+// The foreign keys referencing a table created later, within cycles of references,
+// are added by ALTER TABLE statements once the tables are filled, except with
+// SQLite which accepts references to tables not created yet.
+// The script is not atomic with MySQL, which implicitly commits the transaction
+// at each CREATE TABLE and ALTER TABLE statement.
+func (d *Databook) SQL(opts SQLOptions) *Exportable {
+	sheets := d.sqlSheetOrder()
+	tables := d.sqlTableNames()
+	order := make(map[string]int, len(sheets))
+	for i, s := range sheets {
+		order[s.title] = i
+	}
+
 	b := newBuffer()
+	b.WriteString(sqlBegin(opts.Dialect) + "\n\n")
+	columns := make([][]sqlColumn, len(sheets))
+	alters := make([]string, 0)
+	for i, s := range sheets {
+		deferred := make(map[int]bool)
+		for j, fk := range s.dataset.keys.foreign {
+			if k, ok := order[fk.Table]; ok && k > i && opts.Dialect != DialectSQLite {
+				deferred[j] = true
+				alters = append(alters, "ALTER TABLE "+opts.Dialect.QuoteIdentifier(tables[s.title])+
+					" ADD "+foreignKeyClause(fk, opts.Dialect, tables)+";\n")
+			}
+		}
+		tableSQL, cols := s.dataset.createTable(tables[s.title], opts.Dialect, tables, deferred)
+		columns[i] = cols
+		if opts.Content != SQLDataOnly {
+			b.WriteString(tableSQL)
+		}
+	}
+	if opts.Content != SQLSchemaOnly {
+		for i, s := range sheets {
+			if s.dataset.rows == 0 {
+				continue
+			}
+			s.dataset.writeData(b, tables[s.title], columns[i], opts)
+			b.WriteString("\n")
+		}
+	}
+	if opts.Content != SQLDataOnly && len(alters) > 0 {
+		b.WriteString(strings.Join(alters, "") + "\n")
+	}
+	b.WriteString("COMMIT;\n")
+
+	return newExportable(b)
+}
+
+// sqlTableNames returns the table names of the sheets by title, made of ASCII
+// letters, digits and underscores, an underscore replacing each other character,
+// and not starting with a digit. Sheets whose names would collide are suffixed
+// by a number.
+func (d *Databook) sqlTableNames() map[string]string {
+	tables := make(map[string]string, len(d.sheets))
+	used := make(map[string]bool, len(d.sheets))
+	for i, s := range d.sheets {
+		name := make([]byte, 0, len(s.title))
+		for _, c := range s.title {
+			if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+				name = append(name, byte(c))
+			} else {
+				name = append(name, '_')
+			}
+		}
+		base := string(name)
+		if base == "" {
+			base = "sheet" + strconv.Itoa(i+1)
+		} else if base[0] >= '0' && base[0] <= '9' {
+			base = "_" + base
+		}
+		table := base
+		for n := 2; used[table]; n++ {
+			table = base + "_" + strconv.Itoa(n)
+		}
+		used[table] = true
+		tables[s.title] = table
+	}
+	return tables
+}
 
-	tableSQL, columnTypes, columnValues := d.createTable(table, dbType)
-	b.WriteString(tableSQL)
+// sqlSheetOrder returns the sheets in the order their tables must be created,
+// the sheets referenced by foreign keys coming before the sheets referencing them,
+// except within cycles of references.
+func (d *Databook) sqlSheetOrder() []Sheet {
+	sheets := make([]Sheet, 0, len(d.sheets))
+	done := make(map[string]bool, len(d.sheets))
+	visiting := make(map[string]bool)
+	var visit func(s Sheet)
+	visit = func(s Sheet) {
+		if done[s.title] || visiting[s.title] {
+			return
+		}
+		visiting[s.title] = true
+		for _, fk := range s.dataset.keys.foreign {
+			if i := d.indexOfSheet(fk.Table); i != -1 {
+				visit(d.sheets[i])
+			}
+		}
+		visiting[s.title] = false
+		done[s.title] = true
+		sheets = append(sheets, s)
+	}
+	for _, s := range d.sheets {
+		visit(s)
+	}
+	return sheets
+}
 
-	reg, _ := regexp.Compile("[']")
-	// inserts
+// sqlBegin returns the statement starting a transaction.
+func sqlBegin(dialect SQLDialect) string {
+	if dialect == DialectSQLServer {
+		return "BEGIN TRANSACTION;"
+	}
+	return "BEGIN;"
+}
+
+// writeData writes the statements loading the rows into a table, the synthetic
+// id column being left to the database.
+func (d *Dataset) writeData(b *bytes.Buffer, table string, columns []sqlColumn, opts SQLOptions) {
+	// only Postgres has COPY FROM STDIN, the other dialects fall back to INSERT
+	if opts.Copy && opts.Dialect == DialectPostgres {
+		d.writeCopy(b, table, columns)
+	} else {
+		d.writeInserts(b, table, columns, opts)
+	}
+}
+
+// sqlColumnNames returns the quoted names of the columns of the Dataset.
+func (d *Dataset) sqlColumnNames(dialect SQLDialect) []string {
+	names := make([]string, d.cols)
+	for j, h := range d.headers {
+		names[j] = dialect.QuoteIdentifier(h)
+	}
+	return names
+}
+
+// upsertKeys returns the upsert keys which are headers of the Dataset.
+func (d *Dataset) upsertKeys(keys []string) []string {
+	known := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if containsString(k, d.headers) {
+			known = append(known, k)
+		}
+	}
+	return known
+}
+
+// writeInserts writes the INSERT statements of the rows, by batches.
+func (d *Dataset) writeInserts(b *bytes.Buffer, table string, columns []sqlColumn, opts SQLOptions) {
+	tuples := make([]string, d.rows)
+	for i := range d.data {
+		values := make([]string, len(columns))
+		for j, col := range columns {
+			values[j] = d.sqlValue(col.values[i], col.kind, opts.Dialect)
+		}
+		tuples[i] = "(" + strings.Join(values, ", ") + ")"
+	}
+	opts.UpsertKeys = d.upsertKeys(opts.UpsertKeys)
+	insert := "INSERT INTO " + opts.Dialect.QuoteIdentifier(table) + " (" +
+		strings.Join(d.sqlColumnNames(opts.Dialect), ", ") + ") VALUES"
+
+	size := opts.BatchSize
+	if size < 1 {
+		size = 1
+	}
+	for start := 0; start < len(tuples); start += size {
+		end := start + size
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		if len(opts.UpsertKeys) > 0 {
+			b.WriteString(d.upsertStatement(table, tuples[start:end], opts))
+		} else {
+			b.WriteString(insert + joinSQLTuples(tuples[start:end]) + ";\n")
+		}
+	}
+}
+
+// joinSQLTuples joins the tuples of values of an INSERT statement, one per line
+// if there are several of them.
+func joinSQLTuples(tuples []string) string {
+	if len(tuples) == 1 {
+		return tuples[0]
+	}
+	return "\n\t" + strings.Join(tuples, ",\n\t")
+}
+
+// upsertStatement returns the statement inserting rows or updating the existing
+// ones having the same upsert keys.
+func (d *Dataset) upsertStatement(table string, tuples []string, opts SQLOptions) string {
+	dialect := opts.Dialect
+	names := d.sqlColumnNames(dialect)
+	keys := make([]string, len(opts.UpsertKeys))
+	for i, k := range opts.UpsertKeys {
+		keys[i] = dialect.QuoteIdentifier(k)
+	}
+	updated := make([]string, 0, d.cols)
+	for _, h := range d.headers {
+		if !containsString(h, opts.UpsertKeys) {
+			updated = append(updated, dialect.QuoteIdentifier(h))
+		}
+	}
+
+	set := func(value func(name string) string) string {
+		assignments := make([]string, len(updated))
+		for i, name := range updated {
+			assignments[i] = name + " = " + value(name)
+		}
+		return strings.Join(assignments, ", ")
+	}
+
+	quotedTable := dialect.QuoteIdentifier(table)
+	insert := "INSERT INTO " + quotedTable + " (" + strings.Join(names, ", ") + ") VALUES" + joinSQLTuples(tuples)
+	switch dialect {
+	case DialectMySQL:
+		if len(updated) == 0 {
+			updated = keys[:1]
+		}
+		return insert + "\nON DUPLICATE KEY UPDATE " +
+			set(func(name string) string { return "VALUES(" + name + ")" }) + ";\n"
+	case DialectSQLServer:
+		on := make([]string, len(keys))
+		for i, k := range keys {
+			on[i] = "target." + k + " = source." + k
+		}
+		sourceNames := make([]string, len(names))
+		for i, name := range names {
+			sourceNames[i] = "source." + name
+		}
+		merge := "MERGE INTO " + quotedTable + " AS target\nUSING (VALUES\n\t" + strings.Join(tuples, ",\n\t") +
+			") AS source (" + strings.Join(names, ", ") + ")\nON " + strings.Join(on, " AND ")
+		if len(updated) > 0 {
+			merge += "\nWHEN MATCHED THEN UPDATE SET " + set(func(name string) string { return "source." + name })
+		}
+		return merge + "\nWHEN NOT MATCHED THEN INSERT (" + strings.Join(names, ", ") + ") VALUES (" +
+			strings.Join(sourceNames, ", ") + ");\n"
+	}
+	conflict := insert + "\nON CONFLICT (" + strings.Join(keys, ", ") + ") DO "
+	if len(updated) == 0 {
+		return conflict + "NOTHING;\n"
+	}
+	return conflict + "UPDATE SET " + set(func(name string) string { return "EXCLUDED." + name }) + ";\n"
+}
+
+// writeCopy writes a Postgres COPY FROM STDIN statement in text format loading the rows.
+func (d *Dataset) writeCopy(b *bytes.Buffer, table string, columns []sqlColumn) {
+	b.WriteString("COPY " + DialectPostgres.QuoteIdentifier(table) + " (" +
+		strings.Join(d.sqlColumnNames(DialectPostgres), ", ") + ") FROM STDIN;\n")
 	for i := range d.data {
-		b.WriteString("INSERT INTO " + table + " VALUES(" + strconv.Itoa(i+1) + ", ")
-		for j, col := range d.headers {
-			asStr := d.asString(columnValues[col][i])
-			if isStringColumn(columnTypes[col]) {
-				b.WriteString("'" + reg.ReplaceAllString(asStr, "''") + "'")
-			} else if strings.HasPrefix(columnTypes[col], "TIMESTAMP") {
-				if dbType == typeMySQL {
-					b.WriteString("CONVERT_TZ('" + asStr[:10] + " " + asStr[11:19] + "', '" + asStr[len(asStr)-6:] + "', 'SYSTEM')")
-				} else {
-					b.WriteString("'" + asStr + "'") // simpler with Postgres
+		values := make([]string, len(columns))
+		for j, col := range columns {
+			values[j] = d.copyValue(col.values[i])
+		}
+		b.WriteString(strings.Join(values, "\t") + "\n")
+	}
+	b.WriteString("\\.\n")
+}
+
+// copyValue returns the representation of a value in the text format of COPY.
+func (d *Dataset) copyValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return `\N`
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	if s, ok := sqlNumber(v); ok {
+		if s == "NULL" {
+			return `\N`
+		}
+		return s
+	}
+	return copyEscaper.Replace(d.asString(v))
+}
+
+// PreparedInsert returns the text of a prepared statement inserting a row into
+// a table, and the bind arguments of each row of the Dataset.
+func (d *Dataset) PreparedInsert(table string, dialect SQLDialect) (string, [][]interface{}) {
+	columns := d.sqlColumnNames(dialect)
+	placeholders := make([]string, d.cols)
+	for j := range d.headers {
+		placeholders[j] = dialect.Placeholder(j + 1)
+	}
+	statement := "INSERT INTO " + dialect.QuoteIdentifier(table) + " (" + strings.Join(columns, ", ") +
+		") VALUES (" + strings.Join(placeholders, ", ") + ")"
+
+	args := make([][]interface{}, d.rows)
+	for i, r := range d.data {
+		args[i] = evaluatedRow(r)
+	}
+	return statement, args
+}
+
+// sqlValue returns the SQL representation of a value of a column of a given kind.
+func (d *Dataset) sqlValue(v interface{}, kind sqlKind, dialect SQLDialect) string {
+	if v == nil {
+		return "NULL"
+	}
+	switch kind {
+	case sqlNumeric, sqlInt:
+		if s, ok := sqlNumber(v); ok {
+			return s
+		}
+	case sqlBool:
+		if b, ok := v.(bool); ok {
+			if dialect == DialectSQLite || dialect == DialectSQLServer {
+				if b {
+					return "1"
 				}
-			} else {
-				b.WriteString(asStr)
+				return "0"
 			}
-			if j < len(d.headers)-1 {
-				b.WriteString(", ")
+			return strings.ToUpper(strconv.FormatBool(b))
+		}
+	case sqlTime:
+		if t, ok := v.(time.Time); ok {
+			switch dialect {
+			case DialectMySQL:
+				// TIMESTAMP literals are read in the time zone of the session
+				return "CONVERT_TZ('" + t.Format("2006-01-02 15:04:05.999999") + "', '" +
+					t.Format("-07:00") + "', @@session.time_zone)"
+			case DialectSQLServer:
+				return "'" + t.Format("2006-01-02 15:04:05.9999999-07:00") + "'"
 			}
+			// the same format as COPY, keeping the offset and the nanoseconds
+			return "'" + t.Format(time.RFC3339Nano) + "'"
 		}
-		b.WriteString(");\n")
 	}
-	b.WriteString("\nCOMMIT;\n")
+	return dialect.QuoteString(d.asString(v))
+}
 
-	return newExportable(b)
+// sqlNumber returns the SQL representation of a number, NaN and infinities
+// being written as NULL.
+func sqlNumber(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case uint, uint8, uint16, uint32, uint64:
+		return strconv.FormatUint(reflect.ValueOf(n).Uint(), 10), true
+	case float32:
+		if math.IsNaN(float64(n)) || math.IsInf(float64(n), 0) {
+			return "NULL", true
+		}
+		return strconv.FormatFloat(float64(n), 'g', -1, 32), true
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return "NULL", true
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	}
+	if i, ok := toInt64(v); ok {
+		return strconv.FormatInt(i, 10), true
+	}
+	return "", false
 }
 
-func (d *Dataset) createTable(table, dbType string) (string, map[string]string, map[string][]interface{}) {
+// columnSQLOptions returns the NOT NULL and DEFAULT options of a column,
+// given by its field in the Schema.
+func (d *Dataset) columnSQLOptions(col sqlColumn, dialect SQLDialect) string {
+	f, ok := d.fields[col.header]
+	if !ok {
+		return ""
+	}
+	options := ""
+	if !f.Nullable {
+		options += " NOT NULL"
+	}
+	if f.Default != nil {
+		def, err := f.coerce(f.Default)
+		if err != nil {
+			def = f.Default
+		}
+		value := d.sqlValue(def, col.kind, dialect)
+		if dialect == DialectMySQL && col.kind == sqlTime {
+			// MySQL only accepts the CONVERT_TZ expression within parentheses
+			value = "(" + value + ")"
+		}
+		options += " DEFAULT " + value
+	}
+	return options
+}
+
+// sqlIDColumn returns the name of the synthetic id column, "id" prefixed by as
+// many underscores as needed not to clash with a header.
+func (d *Dataset) sqlIDColumn() string {
+	id := "id"
+	for containsString(id, d.headers) {
+		id = "_" + id
+	}
+	return id
+}
+
+// quoteSQLColumns returns a parenthesized list of quoted column names.
+func quoteSQLColumns(names []string, dialect SQLDialect) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = dialect.QuoteIdentifier(n)
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
+
+// foreignKeyClause returns the FOREIGN KEY clause declaring a foreign key, the
+// referenced table being renamed according to tables.
+func foreignKeyClause(fk ForeignKey, dialect SQLDialect, tables map[string]string) string {
+	table := fk.Table
+	if name, ok := tables[table]; ok {
+		table = name
+	}
+	return "FOREIGN KEY " + quoteSQLColumns(fk.Columns, dialect) + " REFERENCES " +
+		dialect.QuoteIdentifier(table) + " " + quoteSQLColumns(fk.References, dialect)
+}
+
+// keyClauses returns the PRIMARY KEY, UNIQUE and FOREIGN KEY clauses declaring
+// the keys of the Dataset, the tables referenced by foreign keys being renamed
+// according to tables. The foreign keys whose index is in deferred are left out.
+func (d *Dataset) keyClauses(dialect SQLDialect, tables map[string]string, deferred map[int]bool) []string {
+	clauses := make([]string, 0)
+	if len(d.keys.primary) > 0 {
+		clauses = append(clauses, "PRIMARY KEY "+quoteSQLColumns(d.keys.primary, dialect))
+	}
+	for _, key := range d.keys.unique {
+		clauses = append(clauses, "UNIQUE "+quoteSQLColumns(key, dialect))
+	}
+	for j, fk := range d.keys.foreign {
+		if !deferred[j] {
+			clauses = append(clauses, foreignKeyClause(fk, dialect, tables))
+		}
+	}
+	return clauses
+}
+
+// createTable returns the CREATE TABLE statement of the Dataset and its columns,
+// the tables referenced by foreign keys being renamed according to tables and
+// the foreign keys whose index is in deferred being left out.
+func (d *Dataset) createTable(table string, dialect SQLDialect, tables map[string]string, deferred map[int]bool) (string, []sqlColumn) {
 	var b bytes.Buffer
-	columnValues := make(map[string][]interface{})
-	columnTypes := make(map[string]string)
+	columns := make([]sqlColumn, 0, d.cols)
 
 	// create table
-	b.WriteString("CREATE TABLE IF NOT EXISTS " + table)
-	if dbType == typePostgres {
-		b.WriteString("\n(\n\tid SERIAL PRIMARY KEY,\n")
+	if dialect == DialectSQLServer {
+		b.WriteString("IF OBJECT_ID(" + dialect.QuoteString(table) + ", N'U') IS NULL\nCREATE TABLE ")
 	} else {
-		b.WriteString("\n(\n\tid INT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n")
+		b.WriteString("CREATE TABLE IF NOT EXISTS ")
 	}
-	for i, h := range d.headers {
-		b.WriteString("\t" + h)
-		t, v := d.columnSQLType(h, dbType)
-		columnValues[h] = v
-		columnTypes[h] = t
-		b.WriteString(" " + t)
-		if i < len(d.headers)-1 {
-			b.WriteString(",")
-		}
-		b.WriteString("\n")
+	b.WriteString(dialect.QuoteIdentifier(table) + "\n(\n\t")
+	lines := make([]string, 0, d.cols+1)
+	if len(d.keys.primary) == 0 {
+		lines = append(lines, dialect.QuoteIdentifier(d.sqlIDColumn())+" "+sqlIDColumns[dialect])
+	}
+	for _, h := range d.headers {
+		col := d.columnSQLType(h, dialect)
+		columns = append(columns, col)
+		lines = append(lines, dialect.QuoteIdentifier(h)+" "+col.sqlType+d.columnSQLOptions(col, dialect))
 	}
+	lines = append(lines, d.keyClauses(dialect, tables, deferred)...)
+	b.WriteString(strings.Join(lines, ",\n\t"))
 
-	b.WriteString(");\n\n")
+	b.WriteString("\n);\n\n")
 
-	return b.String(), columnTypes, columnValues
+	return b.String(), columns
 }
@@ -0,0 +1,281 @@
+package tablib
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// rowStream holds what is shared by the streaming readers and writers:
+// the headers, the constraints and the dynamic columns applied to each row.
+type rowStream struct {
+	dataset *Dataset
+	dynamic map[int]DynamicColumn
+}
+
+// newRowStream creates a new rowStream for the given headers.
+func newRowStream(headers []string) rowStream {
+	return rowStream{NewDataset(headers), make(map[int]DynamicColumn)}
+}
+
+// Headers returns the headers of the streamed rows, including dynamic columns.
+func (s *rowStream) Headers() []string {
+	return s.dataset.headers
+}
+
+// AppendDynamicColumn appends a dynamic column evaluated for each streamed row.
+func (s *rowStream) AppendDynamicColumn(header string, fn DynamicColumn) {
+	s.dynamic[s.dataset.cols] = fn
+	s.dataset.AppendDynamicColumn(header, fn)
+}
+
+// ConstrainColumn adds a constraint to a column, checked for each streamed row.
+func (s *rowStream) ConstrainColumn(header string, constraint ColumnConstraint) {
+	s.dataset.ConstrainColumn(header, constraint)
+}
+
+// AddRowConstraints attaches constraints on whole rows, checked for each streamed row.
+func (s *rowStream) AddRowConstraints(constraints ...RowConstraint) {
+	s.dataset.AddRowConstraints(constraints...)
+}
+
+// AddConstraints attaches constraints to a column, checked for each streamed row.
+// Returns ErrInvalidColumnIndex if the column does not exist.
+func (s *rowStream) AddConstraints(header string, constraints ...Constraint) error {
+	return s.dataset.AddConstraints(header, constraints...)
+}
+
+// buildRow builds a full row from the values of the non dynamic columns.
+func (s *rowStream) buildRow(values []interface{}) ([]interface{}, error) {
+	if len(values) != s.dataset.cols-len(s.dynamic) {
+		return nil, ErrInvalidDimensions
+	}
+	row := make([]interface{}, 0, s.dataset.cols)
+	k := 0
+	for j := 0; j < s.dataset.cols; j++ {
+		if fn, ok := s.dynamic[j]; ok {
+			row = append(row, fn)
+		} else {
+			row = append(row, values[k])
+			k++
+		}
+	}
+	return row, nil
+}
+
+// CSVReader reads a CSV or TSV source row by row from an io.Reader, without
+// loading the whole content in memory. The first record holds the headers.
+type CSVReader struct {
+	rowStream
+	// SkipInvalid makes the reader skip the rows failing to validate the
+	// constraints set on the columns.
+	SkipInvalid bool
+	reader      *csv.Reader
+	pending     []string
+	row         []interface{}
+	tags        []string
+	tagsIndex   int
+	err         error
+}
+
+// NewCSVReader creates a new CSVReader reading CSV content from r.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	return NewCSVReaderWithOptions(r, CSVOptions{})
+}
+
+// NewTSVReader creates a new CSVReader reading TSV content from r.
+func NewTSVReader(r io.Reader) (*CSVReader, error) {
+	return NewCSVReaderWithOptions(r, CSVOptions{Delimiter: '\t'})
+}
+
+// NewCSVReaderWithOptions creates a new CSVReader reading content from r using
+// a specific dialect. If opts.Tags is set, the TagsColumn column, if any, is not
+// part of the rows and the tags it holds are available through Tags.
+func NewCSVReaderWithOptions(r io.Reader, opts CSVOptions) (*CSVReader, error) {
+	reader := opts.newReader(r)
+	first, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	reader.ReuseRecord = true
+
+	headers, pending := first, []string(nil)
+	if opts.NoHeader {
+		headers, pending = opts.headers(len(first)), first
+	}
+	tagsIndex := -1
+	if opts.Tags {
+		for j, h := range headers {
+			if h == TagsColumn {
+				tagsIndex = j
+				headers = append(append(make([]string, 0, len(headers)-1), headers[:j]...), headers[j+1:]...)
+				break
+			}
+		}
+	}
+	return &CSVReader{rowStream: newRowStream(headers), reader: reader, pending: pending,
+		tagsIndex: tagsIndex}, nil
+}
+
+// Next advances the reader to the next row, which is then available through Row.
+// It returns false when there are no more rows or an error occurred, in which
+// case Err returns it.
+func (r *CSVReader) Next() bool {
+	for r.err == nil {
+		var record []string
+		var err error
+		if r.pending != nil {
+			record, r.pending = r.pending, nil
+		} else {
+			record, err = r.reader.Read()
+		}
+		if err != nil {
+			if err != io.EOF {
+				r.err = err
+			}
+			r.row = nil
+			return false
+		}
+
+		tags := make([]string, 0)
+		values := make([]interface{}, 0, len(record))
+		for k, v := range record {
+			if k == r.tagsIndex {
+				tags = decodeTags(v)
+			} else {
+				values = append(values, v)
+			}
+		}
+		row, err := r.buildRow(values)
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if r.SkipInvalid && !r.dataset.rowIsValid(row) {
+			continue
+		}
+		r.row, r.tags = row, tags
+		return true
+	}
+	return false
+}
+
+// Row returns the current row, dynamic columns being evaluated.
+func (r *CSVReader) Row() []interface{} {
+	if r.row == nil {
+		return nil
+	}
+	return evaluatedRow(r.row)
+}
+
+// Tags returns the tags of the current row, read from the TagsColumn column
+// when the Tags option is set.
+func (r *CSVReader) Tags() []string {
+	return r.tags
+}
+
+// Valid returns whether the current row validates the constraints set on the columns.
+func (r *CSVReader) Valid() bool {
+	return r.row != nil && r.dataset.rowIsValid(r.row)
+}
+
+// Err returns the first error encountered while reading, if any.
+func (r *CSVReader) Err() error {
+	return r.err
+}
+
+// NextBatch reads at most size rows and returns them as a new Dataset holding
+// the constraints and dynamic columns of the reader.
+// Returns io.EOF when there are no more rows to read, and ErrInvalidBatchSize
+// if size is lower than 1.
+func (r *CSVReader) NextBatch(size int) (*Dataset, error) {
+	if size < 1 {
+		return nil, ErrInvalidBatchSize
+	}
+	ds := NewDataset(r.dataset.headers)
+	r.dataset.copyConstraintsTo(ds)
+	for ds.rows < size && r.Next() {
+		ds.AppendTagged(r.row, r.tags...)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if ds.rows == 0 {
+		return nil, io.EOF
+	}
+	return ds, nil
+}
+
+// CSVWriter writes rows in CSV or TSV format to an io.Writer as they are appended.
+// The headers are written along with the first row.
+type CSVWriter struct {
+	rowStream
+	// EmptyValue represents the string value to be output if a field cannot be
+	// formatted as a string.
+	EmptyValue    string
+	writer        *csvRecordWriter
+	headerWritten bool
+}
+
+// NewCSVWriter creates a new CSVWriter writing CSV content to w.
+func NewCSVWriter(w io.Writer, headers []string) *CSVWriter {
+	return NewCSVWriterWithOptions(w, headers, CSVOptions{})
+}
+
+// NewTSVWriter creates a new CSVWriter writing TSV content to w.
+func NewTSVWriter(w io.Writer, headers []string) *CSVWriter {
+	return NewCSVWriterWithOptions(w, headers, CSVOptions{Delimiter: '\t'})
+}
+
+// NewCSVWriterWithOptions creates a new CSVWriter writing content to w using
+// a specific dialect.
+func NewCSVWriterWithOptions(w io.Writer, headers []string, opts CSVOptions) *CSVWriter {
+	return &CSVWriter{rowStream: newRowStream(headers), writer: newCSVRecordWriter(w, opts),
+		headerWritten: opts.NoHeader}
+}
+
+// Append writes a row of values, dynamic columns being evaluated.
+// Returns ErrInvalidDimensions if the number of values does not match the
+// number of non dynamic columns, and ErrInvalidDataset if the row fails to
+// validate the constraints set on the columns, in which case it is not written.
+func (w *CSVWriter) Append(values []interface{}) error {
+	row, err := w.buildRow(values)
+	if err != nil {
+		return err
+	}
+	if !w.dataset.rowIsValid(row) {
+		return ErrInvalidDataset
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.dataset.EmptyValue = w.EmptyValue
+	record := make([]string, len(row))
+	for j, v := range evaluatedRow(row) {
+		record[j] = w.dataset.asString(v)
+	}
+	return w.writer.write(record)
+}
+
+// AppendValues writes a row of values, dynamic columns being evaluated.
+func (w *CSVWriter) AppendValues(values ...interface{}) error {
+	return w.Append(values[:])
+}
+
+// Flush writes any buffered data, and the headers if no row has been written,
+// to the underlying io.Writer.
+func (w *CSVWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.writer.flush()
+}
+
+// writeHeader writes the headers if they have not been written yet.
+func (w *CSVWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.writer.write(w.dataset.headers)
+}
@@ -0,0 +1,327 @@
+package tablib
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TagsColumn is the header of the reserved column holding the tags of the rows,
+// added by WithTagsColumn and recognised by the loaders having their Tags option set.
+const TagsColumn = "_tags"
+
+var tagsEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`)
+
+// TagQuery represents a boolean expression on the tags of a row.
+type TagQuery func(tags []string) bool
+
+// ParseTagQuery parses a boolean expression on tags, made of tags combined
+// with the AND, OR and NOT operators (case insensitive) and parentheses,
+// such as "vip AND NOT (churned OR suspended)". NOT binds tighter than AND,
+// which binds tighter than OR. Tags containing spaces or parentheses must be
+// enclosed in double quotes.
+// Returns ErrInvalidTagQuery if the expression cannot be parsed.
+func ParseTagQuery(query string) (TagQuery, error) {
+	tokens, err := tokenizeTagQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &tagQueryParser{tokens: tokens}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, ErrInvalidTagQuery
+	}
+	return q, nil
+}
+
+// FilterQuery filters a Dataset using a boolean expression on tags as parsed by
+// ParseTagQuery, returning a fresh Dataset including only the matching rows.
+// Returns ErrInvalidTagQuery if the expression cannot be parsed.
+func (d *Dataset) FilterQuery(query string) (*Dataset, error) {
+	q, err := ParseTagQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	nd := NewDataset(d.headers)
+	for i, r := range d.data {
+		if q(d.tags[i]) {
+			nd.AppendTagged(r, d.tags[i]...)
+		}
+	}
+	return nd, nil
+}
+
+// Untag removes specific tags from a row at a given index.
+// Returns ErrInvalidRowIndex if the row does not exist.
+func (d *Dataset) Untag(index int, tags ...string) error {
+	if index < 0 || index >= d.rows {
+		return ErrInvalidRowIndex
+	}
+
+	kept := make([]string, 0, len(d.tags[index]))
+	for _, tag := range d.tags[index] {
+		if !isTagged(tag, tags) {
+			kept = append(kept, tag)
+		}
+	}
+	d.tags[index] = kept
+	return nil
+}
+
+// ClearTags removes all the tags from a row at a given index.
+// Returns ErrInvalidRowIndex if the row does not exist.
+func (d *Dataset) ClearTags(index int) error {
+	if index < 0 || index >= d.rows {
+		return ErrInvalidRowIndex
+	}
+
+	d.tags[index] = make([]string, 0)
+	return nil
+}
+
+// AllTags returns all the distinct tags of the Dataset, in order of first appearance.
+func (d *Dataset) AllTags() []string {
+	all := make([]string, 0)
+	for _, tags := range d.tags {
+		for _, tag := range tags {
+			if !isTagged(tag, all) {
+				all = append(all, tag)
+			}
+		}
+	}
+	return all
+}
+
+// TagCounts returns the number of rows carrying each tag.
+func (d *Dataset) TagCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, tags := range d.tags {
+		for i, tag := range tags {
+			if !isTagged(tag, tags[:i]) {
+				counts[tag]++
+			}
+		}
+	}
+	return counts
+}
+
+// WithTagsColumn returns a copy of the Dataset having an additional TagsColumn
+// holding the tags of each row, so that they survive an export. Tags are
+// separated by commas, commas and backslashes in tags being escaped by a backslash,
+// and empty tags being written as \-. The constraints of the Dataset are kept.
+func (d *Dataset) WithTagsColumn() *Dataset {
+	headers := make([]string, 0, d.cols+1)
+	headers = append(headers, d.headers...)
+	nd := NewDataset(append(headers, TagsColumn))
+	for header, format := range d.formats {
+		nd.SetColumnFormat(header, format)
+	}
+	for i, r := range d.data {
+		row := make([]interface{}, 0, d.cols+1)
+		row = append(row, r...)
+		nd.AppendTagged(append(row, encodeTags(d.tags[i])), d.tags[i]...)
+	}
+	d.copyConstraintsTo(nd)
+	return nd
+}
+
+// WithTagsColumn returns a copy of the Databook where each Dataset has an
+// additional TagsColumn holding the tags of its rows.
+func (d *Databook) WithTagsColumn() *Databook {
+	nb := NewDatabook()
+	for _, s := range d.sheets {
+		nb.AddSheet(s.title, s.dataset.WithTagsColumn())
+	}
+	return nb
+}
+
+// restoreTags tags the rows using the values of the TagsColumn column, if any,
+// which is then removed.
+func (d *Dataset) restoreTags() {
+	j := indexOfColumn(TagsColumn, d)
+	if j == -1 {
+		return
+	}
+	for i, r := range d.data {
+		d.tags[i] = decodeTags(r[j])
+	}
+	d.DeleteColumn(TagsColumn)
+}
+
+// encodeTags encodes tags as a single string.
+func encodeTags(tags []string) string {
+	escaped := make([]string, len(tags))
+	for i, tag := range tags {
+		if tag == "" {
+			escaped[i] = `\-`
+		} else {
+			escaped[i] = tagsEscaper.Replace(tag)
+		}
+	}
+	return strings.Join(escaped, ",")
+}
+
+// decodeTags decodes the tags encoded by encodeTags, lists of tags being also
+// accepted for the formats supporting them.
+func decodeTags(value interface{}) []string {
+	tags := make([]string, 0)
+	switch v := value.(type) {
+	case nil:
+		return tags
+	case []interface{}:
+		for _, tag := range v {
+			tags = append(tags, fmt.Sprint(tag))
+		}
+		return tags
+	}
+
+	encoded := fmt.Sprint(value)
+	if encoded == "" {
+		return tags
+	}
+	var tag strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		switch c := encoded[i]; {
+		case c == '\\' && i+1 < len(encoded) && encoded[i+1] == '-':
+			// empty tag
+			i++
+		case c == '\\' && i+1 < len(encoded):
+			i++
+			tag.WriteByte(encoded[i])
+		case c == ',':
+			tags = append(tags, tag.String())
+			tag.Reset()
+		default:
+			tag.WriteByte(c)
+		}
+	}
+	return append(tags, tag.String())
+}
+
+// tagQueryToken represents a token of a tag query, quoted tags being never
+// considered as operators.
+type tagQueryToken struct {
+	value  string
+	quoted bool
+}
+
+// is returns whether the token is a given operator or parenthesis.
+func (t tagQueryToken) is(operator string) bool {
+	return !t.quoted && strings.EqualFold(t.value, operator)
+}
+
+// tokenizeTagQuery splits a tag query into tokens.
+func tokenizeTagQuery(query string) ([]tagQueryToken, error) {
+	tokens := make([]tagQueryToken, 0)
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, tagQueryToken{string(r), false})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, ErrInvalidTagQuery
+			}
+			tokens = append(tokens, tagQueryToken{string(runes[i+1 : end]), true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) &&
+				runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, tagQueryToken{string(runes[i:end]), false})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// tagQueryParser is a recursive descent parser for tag queries.
+type tagQueryParser struct {
+	tokens []tagQueryToken
+	pos    int
+}
+
+// accept consumes the next token if it is a given operator.
+func (p *tagQueryParser) accept(operator string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].is(operator) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *tagQueryParser) parseOr() (TagQuery, error) {
+	q, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left := q
+		q = func(tags []string) bool { return left(tags) || right(tags) }
+	}
+	return q, nil
+}
+
+func (p *tagQueryParser) parseAnd() (TagQuery, error) {
+	q, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left := q
+		q = func(tags []string) bool { return left(tags) && right(tags) }
+	}
+	return q, nil
+}
+
+func (p *tagQueryParser) parseNot() (TagQuery, error) {
+	if p.accept("NOT") {
+		q, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(tags []string) bool { return !q(tags) }, nil
+	}
+	if p.accept("(") {
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, ErrInvalidTagQuery
+		}
+		return q, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, ErrInvalidTagQuery
+	}
+	t := p.tokens[p.pos]
+	if t.is("AND") || t.is("OR") || t.is(")") {
+		return nil, ErrInvalidTagQuery
+	}
+	p.pos++
+	return func(tags []string) bool { return isTagged(t.value, tags) }, nil
+}
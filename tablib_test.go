@@ -2,10 +2,22 @@ package tablib_test
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	tablib "github.com/agrison/go-tablib"
+	"github.com/tealeg/xlsx"
 	. "gopkg.in/check.v1"
 )
 
@@ -74,6 +86,16 @@ func (s *TablibSuite) TestDimensions(c *C) {
 	c.Assert(ds.Headers(), DeepEquals, []string{"firstName", "lastName", "gpa"})
 }
 
+func (s *TablibSuite) TestNewDatasetWithData(c *C) {
+	ds := tablib.NewDatasetWithData([]string{"firstName", "lastName"},
+		[][]interface{}{{"John", "Adams"}, {"George", "Washington"}})
+	ds.AppendTagged([]interface{}{"Thomas", "Jefferson"}, "Virginia")
+	c.Assert(ds.Height(), Equals, 3)
+	c.Assert(ds.Filter("Virginia").Column("firstName"), DeepEquals, []interface{}{"Thomas"})
+	c.Assert(ds.DeleteRow(0), Equals, nil)
+	c.Assert(ds.Filter("Virginia").Column("firstName"), DeepEquals, []interface{}{"Thomas"})
+}
+
 func (s *TablibSuite) TestAppendRow(c *C) {
 	ds := presidentDataset()
 	// too much columns
@@ -120,6 +142,15 @@ func (s *TablibSuite) TestInsert(c *C) {
 	c.Assert(d["firstName"], Equals, "foo")
 	c.Assert(d["lastName"], Equals, "bar")
 	c.Assert(d["gpa"], Equals, 42)
+
+	// InsertTagged inserts the row once
+	c.Assert(ds.InsertTagged(0, []interface{}{"Thomas", "Paine", 70}, "writer"), Equals, nil)
+	c.Assert(ds.Height(), Equals, 5)
+	c.Assert(ds.Column("lastName"), DeepEquals, []interface{}{"Paine", "Adams", "bar", "Washington", "Jefferson"})
+	tags, _ := ds.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"writer"})
+	tags, _ = ds.Tags(1)
+	c.Assert(tags, DeepEquals, []string{})
 }
 
 func (s *TablibSuite) TestInsertColumn(c *C) {
@@ -266,6 +297,255 @@ func (s *TablibSuite) TestStackColumn(c *C) {
 	c.Assert(err, Equals, tablib.ErrInvalidDimensions)
 }
 
+func (s *TablibSuite) TestFilterQuery(c *C) {
+	ds := tablib.NewDataset([]string{"name"})
+	ds.AppendValuesTagged("Alice", "vip", "churned")
+	ds.AppendValuesTagged("Bob", "vip", "New York")
+	ds.AppendValuesTagged("Carol", "churned")
+	ds.AppendValues("Dave")
+
+	df, err := ds.FilterQuery("vip AND NOT churned")
+	c.Assert(err, Equals, nil)
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Bob"})
+	df, _ = ds.FilterQuery(`not (vip or churned) OR "New York"`)
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Bob", "Dave"})
+	for _, q := range []string{"vip AND", "(vip", "vip churned", `"vip`, "OR vip", ""} {
+		_, err = ds.FilterQuery(q)
+		c.Assert(err, Equals, tablib.ErrInvalidTagQuery, Commentf(q))
+	}
+
+	// a row is included once even with several matching tags
+	c.Assert(ds.Filter("vip", "churned").Height(), Equals, 3)
+
+	c.Assert(ds.AllTags(), DeepEquals, []string{"vip", "churned", "New York"})
+	c.Assert(ds.TagCounts(), DeepEquals, map[string]int{"vip": 2, "churned": 2, "New York": 1})
+	c.Assert(ds.Untag(0, "churned"), Equals, nil)
+	tags, _ := ds.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"vip"})
+	c.Assert(ds.ClearTags(1), Equals, nil)
+	tags, _ = ds.Tags(1)
+	c.Assert(tags, DeepEquals, []string{})
+	c.Assert(ds.Untag(4, "vip"), Equals, tablib.ErrInvalidRowIndex)
+	c.Assert(ds.ClearTags(-1), Equals, tablib.ErrInvalidRowIndex)
+
+	ds.DeleteRow(0)
+	tags, _ = ds.Tags(1)
+	c.Assert(tags, DeepEquals, []string{"churned"})
+}
+
+func (s *TablibSuite) TestTagsRoundTrip(c *C) {
+	ds := tablib.NewDataset([]string{"name", "age"})
+	ds.AppendValuesTagged("Alice", 30, "vip", `a,b\c`)
+	ds.AppendValues("Bob", 40)
+	ds.AppendValuesTagged("Carol", 50, "")
+	exp := ds.WithTagsColumn()
+	c.Assert(exp.Headers(), DeepEquals, []string{"name", "age", tablib.TagsColumn})
+	c.Assert(exp.Column(tablib.TagsColumn), DeepEquals, []interface{}{`vip,a\,b\\c`, "", `\-`})
+	c.Assert(ds.Width(), Equals, 2)
+
+	check := func(loaded *tablib.Dataset, err error) {
+		c.Assert(err, Equals, nil)
+		c.Assert(loaded.Width(), Equals, 2)
+		c.Assert(loaded.Column("name"), DeepEquals, []interface{}{"Alice", "Bob", "Carol"})
+		for i, expected := range [][]string{{"vip", `a,b\c`}, {}, {""}} {
+			tags, _ := loaded.Tags(i)
+			c.Assert(tags, DeepEquals, expected)
+		}
+	}
+	csv, _ := exp.CSV()
+	check(tablib.LoadCSVWithOptions(csv.Bytes(), tablib.CSVOptions{Tags: true}))
+	js, _ := exp.JSON()
+	check(tablib.LoadJSONWithOptions(js.Bytes(), tablib.LoadOptions{Tags: true}))
+	yml, _ := exp.YAML()
+	check(tablib.LoadYAMLWithOptions(yml.Bytes(), tablib.LoadOptions{Tags: true}))
+	xml, _ := exp.XML()
+	check(tablib.LoadXMLWithOptions(xml.Bytes(), tablib.LoadOptions{Tags: true}))
+	xlsx, _ := exp.XLSX()
+	check(tablib.LoadXLSXWithOptions(xlsx.Bytes(), tablib.XLSXOptions{Tags: true}))
+
+	// lists of tags are accepted, and the column is kept when not asked for
+	loaded, _ := tablib.LoadJSONWithOptions([]byte(`[{"name": "Dave", "_tags": ["x", "y"]}]`),
+		tablib.LoadOptions{Tags: true})
+	tags, _ := loaded.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"x", "y"})
+	loaded, _ = tablib.LoadCSV(csv.Bytes())
+	c.Assert(loaded.Headers(), DeepEquals, []string{"name", "age", tablib.TagsColumn})
+
+	// rows without tags are not tagged with the missing value
+	loaded, _ = tablib.LoadJSONWithOptions([]byte(`[{"name": "Dave", "_tags": "x"}, {"name": "Eve"}]`),
+		tablib.LoadOptions{Tags: true, MissingValue: "N/A"})
+	tags, _ = loaded.Tags(1)
+	c.Assert(tags, DeepEquals, []string{})
+
+	// streaming readers restore the tags too
+	r, err := tablib.NewCSVReaderWithOptions(bytes.NewReader(csv.Bytes()), tablib.CSVOptions{Tags: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(r.Headers(), DeepEquals, []string{"name", "age"})
+	c.Assert(r.Next(), Equals, true)
+	c.Assert(r.Row(), DeepEquals, []interface{}{"Alice", "30"})
+	c.Assert(r.Tags(), DeepEquals, []string{"vip", `a,b\c`})
+	batch, err := r.NextBatch(2)
+	c.Assert(err, Equals, nil)
+	tags, _ = batch.Tags(1)
+	c.Assert(tags, DeepEquals, []string{""})
+
+	// constraints are kept
+	ds.AddConstraints("age", tablib.Range(0, 45))
+	c.Assert(ds.WithTagsColumn().Valid(), Equals, false)
+
+	db := tablib.NewDatabook()
+	db.AddSheet("people", ds)
+	dbjs, _ := db.WithTagsColumn().JSON()
+	ldb, err := tablib.LoadDatabookJSONWithOptions(dbjs.Bytes(), tablib.LoadOptions{Tags: true})
+	c.Assert(err, Equals, nil)
+	check(ldb.Sheet("people").Dataset(), nil)
+}
+
+func (s *TablibSuite) TestJoin(c *C) {
+	cars := tablib.NewDataset([]string{"Maker", "Model", "Country"})
+	cars.AppendTagged([]interface{}{"Porsche", "991", "DE"}, "fast")
+	cars.AppendValues("Skoda", "Octavia", "CZ")
+	cars.AppendValues("Tesla", "Model S", "US")
+	makers := tablib.NewDataset([]string{"Country", "Maker", "Founded"})
+	makers.AppendValues("DE", "Porsche", 1931)
+	makers.AppendValues("CZ", "Skoda", 1895)
+	makers.AppendValues("IT", "Ferrari", 1939)
+
+	ds, err := cars.Join(makers, []string{"Maker"}, tablib.InnerJoin)
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"Maker", "Model", "Country", "Country_right", "Founded"})
+	c.Assert(ds.Height(), Equals, 2)
+	c.Assert(ds.Column("Founded"), DeepEquals, []interface{}{1931, 1895})
+	tags, _ := ds.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"fast"})
+
+	ds, _ = cars.JoinWithSuffixes(makers, []string{"Maker", "Country"}, tablib.LeftJoin, "_l", "_r")
+	c.Assert(ds.Headers(), DeepEquals, []string{"Maker", "Model", "Country", "Founded"})
+	c.Assert(ds.Column("Founded"), DeepEquals, []interface{}{1931, 1895, nil})
+
+	ds, _ = cars.Join(makers, []string{"Maker"}, tablib.RightJoin)
+	c.Assert(ds.Column("Maker"), DeepEquals, []interface{}{"Porsche", "Skoda", "Ferrari"})
+	c.Assert(ds.Column("Model"), DeepEquals, []interface{}{"991", "Octavia", nil})
+
+	ds, _ = cars.Join(makers, []string{"Maker"}, tablib.FullJoin)
+	c.Assert(ds.Column("Maker"), DeepEquals, []interface{}{"Porsche", "Skoda", "Tesla", "Ferrari"})
+
+	_, err = cars.Join(makers, []string{"Model"}, tablib.InnerJoin)
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+	_, err = cars.Join(makers, nil, tablib.InnerJoin)
+	c.Assert(err, Equals, tablib.ErrNoJoinKey)
+	c.Assert(cars.AppendColumn("Country_right", []interface{}{"DE", "CZ", "US"}), Equals, nil)
+	_, err = cars.Join(makers, []string{"Maker"}, tablib.InnerJoin)
+	c.Assert(err, Equals, tablib.ErrDuplicateHeader)
+
+	left := tablib.NewDataset([]string{"a", "b", "at"})
+	left.AppendValues("x\x00s:y", "z", time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC))
+	left.AppendValues("x", "y", time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC))
+	right := tablib.NewDataset([]string{"a", "b", "at", "found"})
+	right.AppendValues("x", "y\x00s:z", time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC), true)
+	right.AppendValues("x", "y", time.Date(2500, 1, 1, 1, 0, 0, 0, time.FixedZone("", 3600)), true)
+	ds, err = left.Join(right, []string{"a", "b", "at"}, tablib.LeftJoin)
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Column("found"), DeepEquals, []interface{}{nil, true})
+
+	// large integral floats match the integers they are equal to
+	left = tablib.NewDataset([]string{"n"})
+	left.AppendValues(int64(1e18))
+	left.AppendValues(uint64(1 << 63))
+	left.AppendValues(int64(-1 << 63))
+	right = tablib.NewDataset([]string{"n", "found"})
+	right.AppendValues(float64(1e18), true)
+	right.AppendValues(float64(1<<63), true)
+	right.AppendValues(float64(-1<<63), true)
+	ds, err = left.Join(right, []string{"n"}, tablib.LeftJoin)
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Column("found"), DeepEquals, []interface{}{true, true, true})
+}
+
+func (s *TablibSuite) TestGroupBy(c *C) {
+	ds := tablib.NewDataset([]string{"customer", "product", "amount"})
+	ds.AppendValues("alice", "book", 12)
+	ds.AppendValues("bob", "pen", 1.5)
+	ds.AppendValues("alice", "pen", int64(2))
+	ds.AppendValues("alice", "book", nil)
+	ds.AppendValues("bob", "ink", 3.5)
+
+	g, err := ds.GroupBy("customer")
+	c.Assert(err, Equals, nil)
+	c.Assert(g.Len(), Equals, 2)
+	agg, err := g.Aggregate(tablib.Count("amount"), tablib.Sum("amount").As("total"),
+		tablib.Mean("amount"), tablib.Min("amount"), tablib.Max("amount"),
+		tablib.First("product"), tablib.Last("product"), tablib.DistinctCount("product"),
+		tablib.Aggregate("product", "products", func(values []interface{}) interface{} {
+			return len(values)
+		}))
+	c.Assert(err, Equals, nil)
+	c.Assert(agg.Headers(), DeepEquals, []string{"customer", "count(amount)", "total", "mean(amount)",
+		"min(amount)", "max(amount)", "first(product)", "last(product)", "distinct(product)", "products"})
+	c.Assert(validRowAt(agg, 0), DeepEquals, map[string]interface{}{"customer": "alice",
+		"count(amount)": 2, "total": int64(14), "mean(amount)": 7.0, "min(amount)": int64(2),
+		"max(amount)": 12, "first(product)": "book", "last(product)": "book",
+		"distinct(product)": 2, "products": 3})
+	c.Assert(validRowAt(agg, 1)["total"], Equals, 5.0)
+
+	g, _ = ds.GroupBy("customer", "product")
+	c.Assert(g.Len(), Equals, 4)
+	_, err = g.Aggregate(tablib.Sum("price"))
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+	_, err = ds.GroupBy("price")
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+
+	// overflowing integer sums fall back to float64
+	c.Assert(tablib.Sum("n").Fn([]interface{}{int64(math.MaxInt64), 1}), Equals, float64(math.MaxInt64)+1)
+	c.Assert(tablib.Sum("n").Fn([]interface{}{int64(math.MinInt64), -1, 2}), Equals, float64(math.MinInt64)+1)
+	c.Assert(tablib.Sum("n").Fn([]interface{}{int64(math.MaxInt64), -1, 1}), Equals, int64(math.MaxInt64))
+}
+
+func (s *TablibSuite) TestPivotAndMelt(c *C) {
+	ds := tablib.NewDataset([]string{"city", "year", "sales"})
+	ds.AppendTagged([]interface{}{"Paris", 2015, 10}, "fr")
+	ds.AppendTagged([]interface{}{"Paris", 2016, 12}, "capital")
+	ds.AppendValues("Lyon", 2015, 4)
+	ds.AppendValues("Lyon", 2015, 3)
+
+	wide, err := ds.Pivot("city", "year", "sales", nil)
+	c.Assert(err, Equals, nil)
+	c.Assert(wide.Headers(), DeepEquals, []string{"city", "2015", "2016"})
+	c.Assert(validRowAt(wide, 0), DeepEquals, map[string]interface{}{"city": "Paris", "2015": 10, "2016": 12})
+	c.Assert(validRowAt(wide, 1), DeepEquals, map[string]interface{}{"city": "Lyon", "2015": 4, "2016": nil})
+	tags, _ := wide.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"fr", "capital"})
+
+	wide, _ = ds.Pivot("city", "year", "sales", tablib.Sum("sales").Fn)
+	c.Assert(validRowAt(wide, 1)["2015"], Equals, int64(7))
+
+	long, err := wide.Melt([]string{"city"}, nil)
+	c.Assert(err, Equals, nil)
+	c.Assert(long.Headers(), DeepEquals, []string{"city", "variable", "value"})
+	c.Assert(long.Height(), Equals, 4)
+	c.Assert(validRowAt(long, 1), DeepEquals, map[string]interface{}{"city": "Paris", "variable": "2016", "value": int64(12)})
+	tags, _ = long.Tags(1)
+	c.Assert(tags, DeepEquals, []string{"fr", "capital"})
+
+	_, err = ds.Pivot("city", "month", "sales", nil)
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+	_, err = ds.Melt([]string{"city"}, []string{"month"})
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+
+	ds.AppendValues("Nice", "2015", 1)
+	_, err = ds.Pivot("city", "year", "sales", nil)
+	c.Assert(err, Equals, tablib.ErrDuplicateHeader)
+	ds = tablib.NewDataset([]string{"city", "year", "sales"})
+	ds.AppendValues("Nice", "city", 1)
+	_, err = ds.Pivot("city", "year", "sales", nil)
+	c.Assert(err, Equals, tablib.ErrDuplicateHeader)
+	renamed := tablib.NewDataset([]string{"value", "sales"})
+	_, err = renamed.Melt([]string{"value"}, nil)
+	c.Assert(err, Equals, tablib.ErrDuplicateHeader)
+	_, err = renamed.Melt([]string{"sales", "sales"}, []string{"value"})
+	c.Assert(err, Equals, tablib.ErrDuplicateHeader)
+}
+
 func (s *TablibSuite) TestFiltering(c *C) {
 	ds := presidentDatasetWithTags()
 	df := ds.Filter("Massachusetts")
@@ -293,6 +573,35 @@ func (s *TablibSuite) TestFiltering(c *C) {
 	c.Assert(tags[0], Equals, "Virginia")
 }
 
+func (s *TablibSuite) TestWhere(c *C) {
+	ds := tablib.NewDataset([]string{"name", "age", "country"})
+	ds.AppendTagged([]interface{}{"Alice", 42, "FR"}, "vip")
+	ds.AppendValues("Bob", 25, "BE")
+	ds.AppendValues("Carol", nil, "FR")
+	ds.AppendValues("Dave", 35.5, "US")
+	ds.AppendValues("Eve", "unknown", "BE")
+	ds.ConstrainColumn("age", func(val interface{}) bool { return val != nil })
+
+	adults := tablib.Col("age").Gt(30).And(tablib.Col("country").In("FR", "BE"))
+	df := ds.Where(adults)
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Alice"})
+	tags, _ := df.Tags(0)
+	c.Assert(tags, DeepEquals, []string{"vip"})
+	c.Assert(df.HasAnyConstraint(), Equals, true)
+
+	df = ds.Where(tablib.Col("age").Lt(40).Or(tablib.Col("age").IsNil()))
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Bob", "Carol", "Dave"})
+	df = ds.Where(tablib.Col("country").Eq("FR").Not())
+	c.Assert(df.Height(), Equals, 3)
+	df = ds.Where(tablib.Col("name").Matches(regexp.MustCompile("^[A-C]")).And(tablib.Col("age").Between(20, 30)))
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Bob"})
+	df = ds.Where(func(row tablib.Row) bool { return len(row["name"].(string)) == 3 })
+	c.Assert(df.Column("name"), DeepEquals, []interface{}{"Bob", "Eve"})
+
+	c.Assert(ds.TagWhere(adults.Or(tablib.Col("country").Eq("US")), "target"), Equals, 2)
+	c.Assert(ds.Filter("target").Column("name"), DeepEquals, []interface{}{"Alice", "Dave"})
+}
+
 func (s *TablibSuite) TestSort(c *C) {
 	ds := presidentDataset().Sort("gpa")
 	c.Assert(ds.Height(), Equals, 3)
@@ -328,6 +637,74 @@ func (s *TablibSuite) TestSort(c *C) {
 	c.Assert(r["lastName"], Equals, "Adams")
 }
 
+func (s *TablibSuite) TestSortBy(c *C) {
+	ds := tablib.NewDataset([]string{"file", "size", "owner"})
+	ds.AppendValues("file10.txt", 3, "bob")
+	ds.AppendValues("File2.txt", nil, "alice")
+	ds.AppendValues("file1.txt", 3, "alice")
+	ds.AppendValues("file3.txt", int64(7), "bob")
+
+	sorted, err := ds.SortBy(tablib.SortKey{Column: "file", Natural: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(sorted.Column("file"), DeepEquals, []interface{}{"file1.txt", "File2.txt", "file3.txt", "file10.txt"})
+
+	// only ASCII digits are compared by value
+	arabic := tablib.NewDataset([]string{"file"})
+	arabic.AppendValues("v\u0662")
+	arabic.AppendValues("v\u0661\u0660")
+	sorted, _ = arabic.SortBy(tablib.SortKey{Column: "file", Natural: true})
+	c.Assert(sorted.Column("file"), DeepEquals, []interface{}{"v\u0661\u0660", "v\u0662"})
+
+	sorted, _ = ds.SortBy(tablib.SortKey{Column: "size", Descending: true, NullsFirst: true},
+		tablib.SortKey{Column: "owner"})
+	c.Assert(sorted.Column("file"), DeepEquals, []interface{}{"File2.txt", "file3.txt", "file1.txt", "file10.txt"})
+
+	// stable, nils last by default
+	sorted, _ = ds.SortBy(tablib.SortKey{Column: "size"})
+	c.Assert(sorted.Column("file"), DeepEquals, []interface{}{"file10.txt", "file1.txt", "file3.txt", "File2.txt"})
+
+	sorted = ds.SortFunc(func(a, b map[string]interface{}) bool {
+		return len(a["owner"].(string)) < len(b["owner"].(string))
+	})
+	c.Assert(sorted.Column("owner"), DeepEquals, []interface{}{"bob", "bob", "alice", "alice"})
+
+	_, err = ds.SortBy(tablib.SortKey{Column: "date"})
+	c.Assert(err, Equals, tablib.ErrInvalidColumnIndex)
+	c.Assert(tablib.NewDataset([]string{"a"}).Sort("a").Height(), Equals, 0)
+}
+
+func (s *TablibSuite) TestCompare(c *C) {
+	now := time.Now()
+	ordered := []interface{}{nil, false, true, math.NaN(), int8(-5), -1.5, 0, uint64(1), 1.5,
+		int64(math.MaxInt64), uint64(math.MaxUint64), "", "a", "b", now, now.Add(time.Second), []int{1}}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			c.Assert(tablib.Compare(a, b), Equals, expected, Commentf("%v <=> %v", a, b))
+		}
+	}
+	c.Assert(tablib.Compare(1, 1.0), Equals, 0)
+	c.Assert(tablib.Compare(uint8(2), int64(2)), Equals, 0)
+	c.Assert(tablib.Compare(int64(1<<53), float64(1<<53)), Equals, 0)
+	c.Assert(tablib.Compare(int64(1<<53+1), float64(1<<53)), Equals, 1)
+	c.Assert(tablib.Compare(float64(1<<53), int64(1<<53+1)), Equals, -1)
+	c.Assert(tablib.Compare(-int64(1<<53+1), -float64(1<<53)), Equals, -1)
+	c.Assert(tablib.Compare(uint64(math.MaxUint64), math.Inf(1)), Equals, -1)
+
+	// heterogeneous columns, as loaded from JSON or YAML, never panic
+	ds := tablib.NewDataset([]string{"value"})
+	for _, v := range []interface{}{"b", 2, nil, 1.5, true, "a"} {
+		ds.AppendValues(v)
+	}
+	c.Assert(ds.Sort("value").Column("value"), DeepEquals, []interface{}{true, 1.5, 2, "a", "b", nil})
+	c.Assert(ds.SortReverse("value").Column("value"), DeepEquals, []interface{}{"b", "a", 2, 1.5, true, nil})
+}
+
 func mustBeYoung(val interface{}) bool {
 	return val.(int) <= 50
 }
@@ -371,6 +748,200 @@ func (s *TablibSuite) TestValid(c *C) {
 	c.Assert(ds.ValidationErrors[1].Column, Equals, 2)
 }
 
+func (s *TablibSuite) TestConstraints(c *C) {
+	ds := tablib.NewDataset([]string{"name", "age", "country", "born"})
+	ds.AppendValues("Alice", 30, "FR", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC))
+	ds.AppendValues("Bob", 12.5, "XX", time.Date(1890, 1, 1, 0, 0, 0, 0, time.UTC))
+	ds.AppendValues(nil, int64(30), nil, "yesterday")
+	ds.AppendValues("Alexander", nil, "BE", nil)
+
+	c.Assert(ds.AddConstraints("unknown", tablib.NotNull()), Equals, tablib.ErrInvalidColumnIndex)
+	ds.AddConstraints("name", tablib.NotNull(), tablib.MaxLength(5), tablib.Regexp(regexp.MustCompile("^[A-Z]")))
+	ds.AddConstraints("age", tablib.OfType(tablib.ColumnTypeInt), tablib.Range(18, 99), tablib.Unique())
+	ds.AddConstraints("country", tablib.OneOf("FR", "BE"))
+	ds.AddConstraints("born", tablib.DateBetween(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	c.Assert(ds.Constraints("age"), HasLen, 3)
+
+	c.Assert(ds.Valid(), Equals, false)
+	type failure struct {
+		row        int
+		header     string
+		constraint string
+		value      interface{}
+	}
+	failures := make([]failure, 0)
+	for _, e := range ds.ValidationErrors {
+		failures = append(failures, failure{e.Row, e.Header, e.Constraint, e.Value})
+	}
+	c.Assert(failures, DeepEquals, []failure{
+		{1, "age", "of_type", 12.5},
+		{1, "age", "range", 12.5},
+		{1, "country", "one_of", "XX"},
+		{1, "born", "date_between", time.Date(1890, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{2, "name", "not_null", nil},
+		{2, "age", "unique", int64(30)},
+		{2, "born", "date_between", "yesterday"},
+		{3, "name", "max_length", "Alexander"},
+	})
+	e := ds.ValidationErrors[1]
+	c.Assert(e.Column, Equals, 1)
+	c.Assert(e.Message, Equals, "must be between 18 and 99")
+	c.Assert(e.Error(), Equals, `tablib: row 1, column "age": 12.5 must be between 18 and 99 (range)`)
+
+	c.Assert(ds.ValidFailFast(), Equals, false)
+	c.Assert(ds.ValidSubset().Column("name"), DeepEquals, []interface{}{"Alice"})
+	c.Assert(ds.InvalidSubset().Height(), Equals, 3)
+
+	ds = tablib.NewDataset([]string{"code"})
+	ds.AppendValues("A1")
+	ds.AppendValues(7)
+	ds.AppendValues(true)
+	ds.AddConstraints("code", tablib.AnyOf(tablib.Regexp(regexp.MustCompile("^[A-Z][0-9]$")), tablib.OfType(tablib.ColumnTypeInt)))
+	c.Assert(ds.Valid(), Equals, false)
+	c.Assert(ds.ValidationErrors, HasLen, 1)
+	c.Assert(ds.ValidationErrors[0].Row, Equals, 2)
+	c.Assert(ds.ValidationErrors[0].Message, Equals, "must match ^[A-Z][0-9]$ or must be of type int64")
+}
+
+func (s *TablibSuite) TestRowConstraints(c *C) {
+	day := func(d int) time.Time { return time.Date(2016, 3, d, 0, 0, 0, 0, time.UTC) }
+	ds := tablib.NewDataset([]string{"start", "end", "vip", "discount"})
+	ds.AppendValues(day(1), day(3), true, 10)
+	ds.AppendValues(day(5), day(2), false, 0)
+	ds.AppendValues(day(1), day(2), false, 20)
+	ds.AppendValues(day(4), day(1), nil, 5)
+
+	ds.AddConstraints("discount", tablib.Range(0, 15))
+	ds.AddRowConstraints(
+		tablib.NewRowConstraint("end_after_start", "end must be after start", func(r tablib.Row) bool {
+			return r["end"].(time.Time).After(r["start"].(time.Time))
+		}, "start", "end"),
+		tablib.NewRowConstraint("discount_for_vip", "discount only for vip",
+			tablib.Col("discount").Eq(0).Or(tablib.Col("vip").Eq(true)), "vip", "discount"))
+	c.Assert(ds.RowConstraints(), HasLen, 2)
+	c.Assert(ds.HasAnyConstraint(), Equals, true)
+
+	c.Assert(ds.Valid(), Equals, false)
+	c.Assert(ds.ValidationErrors, HasLen, 5)
+	e := ds.ValidationErrors[0]
+	c.Assert(e.Row, Equals, 1)
+	c.Assert(e.Column, Equals, -1)
+	c.Assert(e.Columns, DeepEquals, []string{"start", "end"})
+	c.Assert(e.Value, DeepEquals, tablib.Row{"start": day(5), "end": day(2)})
+	c.Assert(e.Error(), Equals, `tablib: row 1, columns ["start" "end"]: end must be after start (end_after_start)`)
+	// cell errors come first in a row
+	c.Assert(ds.ValidationErrors[1].Constraint, Equals, "range")
+	c.Assert(ds.ValidationErrors[1].Columns, DeepEquals, []string{"discount"})
+	c.Assert(ds.ValidationErrors[2].Constraint, Equals, "discount_for_vip")
+	c.Assert(ds.ValidationErrors[3].Row, Equals, 3)
+	c.Assert(ds.ValidationErrors[4].Row, Equals, 3)
+
+	c.Assert(ds.ValidFailFast(), Equals, false)
+	c.Assert(ds.ValidSubset().Height(), Equals, 1)
+	c.Assert(ds.InvalidSubset().Height(), Equals, 3)
+}
+
+func (s *TablibSuite) TestValidationReport(c *C) {
+	ds := tablib.NewDataset([]string{"name", "min", "max"})
+	ds.AppendValues("a<b", 1, 5)
+	ds.AppendValues("c", 7, 3)
+	ds.AppendValues(nil, 2, 30)
+	ds.AddConstraints("name", tablib.NotNull())
+	ds.AddConstraints("max", tablib.Range(0, 10))
+	ds.AddRowConstraints(tablib.NewRowConstraint("ordered", "min must be <= max", func(r tablib.Row) bool {
+		return r["min"].(int) <= r["max"].(int)
+	}, "min", "max"))
+
+	c.Assert(tablib.NewDataset([]string{"a"}).Validate().Valid(), Equals, true)
+	report := ds.Validate()
+	c.Assert(report.Valid(), Equals, false)
+	c.Assert(report.Errors, DeepEquals, ds.ValidationErrors)
+
+	csv, err := report.CSV()
+	c.Assert(err, Equals, nil)
+	c.Assert(csv.String(), Equals, `row,column,value,rule,message
+1,"min, max","min=7, max=3",ordered,min must be <= max
+2,name,,not_null,must not be null
+2,max,30,range,must be between 0 and 10
+`)
+	js, _ := report.JSON()
+	c.Assert(js.String(), Equals, `[{"column":"min, max","message":"min must be \u003c= max","row":1,"rule":"ordered","value":"min=7, max=3"},`+
+		`{"column":"name","message":"must not be null","row":2,"rule":"not_null","value":null},`+
+		`{"column":"max","message":"must be between 0 and 10","row":2,"rule":"range","value":30}]`)
+	c.Assert(report.HTML().String(), Equals, `<table class="table table-striped">
+	<thead>
+		<tr>
+			<th>row</th>
+			<th>column</th>
+			<th>value</th>
+			<th>rule</th>
+			<th>message</th>
+		</tr>
+	</thead>
+	<tbody>
+		<tr>
+			<td>1</td>
+			<td>min, max</td>
+			<td>min=7, max=3</td>
+			<td>ordered</td>
+			<td>min must be &lt;= max</td>
+		</tr>
+		<tr>
+			<td>2</td>
+			<td>name</td>
+			<td></td>
+			<td>not_null</td>
+			<td>must not be null</td>
+		</tr>
+		<tr>
+			<td>2</td>
+			<td>max</td>
+			<td>30</td>
+			<td>range</td>
+			<td>must be between 0 and 10</td>
+		</tr>
+	</tbody>
+</table>`)
+	tagged := tablib.NewDataset([]string{"tag"})
+	tagged.AppendValues("<script>alert(1)</script>")
+	tagged.AddConstraints("tag", tablib.MaxLength(3))
+	c.Assert(tagged.Validate().HTML().String(), Matches, `(?s).*<td>&lt;script&gt;alert\(1\)&lt;/script&gt;</td>.*`)
+	c.Assert(tagged.Validate().HTML().String(), Not(Matches), `(?s).*<script>.*`)
+
+	summary, _ := report.Summary().CSV()
+	c.Assert(summary.String(), Equals, "column,rule,count\n\"min, max\",ordered,1\nname,not_null,1\nmax,range,1\n")
+	c.Assert(report.CountsByColumn(), DeepEquals, map[string]int{"name": 1, "min": 1, "max": 2})
+	c.Assert(report.CountsByRule(), DeepEquals, map[string]int{"ordered": 1, "not_null": 1, "range": 1})
+
+	c.Assert(report.HighlightHTML().String(), Equals, `<table class="table table-striped">
+	<thead>
+		<tr>
+			<th>name</th>
+			<th>min</th>
+			<th>max</th>
+		</tr>
+	</thead>
+	<tbody>
+		<tr>
+			<td>a&lt;b</td>
+			<td>1</td>
+			<td>5</td>
+		</tr>
+		<tr>
+			<td>c</td>
+			<td class="invalid" title="min must be &lt;= max (ordered)">7</td>
+			<td class="invalid" title="min must be &lt;= max (ordered)">3</td>
+		</tr>
+		<tr>
+			<td class="invalid" title="must not be null (not_null)"></td>
+			<td>2</td>
+			<td class="invalid" title="must be between 0 and 10 (range)">30</td>
+		</tr>
+	</tbody>
+</table>`)
+}
+
 func (s *TablibSuite) TestValidSubset(c *C) {
 	ds := presidentDatasetWithTags()
 
@@ -454,6 +1025,75 @@ François`+"\t"+`Hollande`+"\t"+`34
 `)
 }
 
+func (s *TablibSuite) TestCSVWithOptions(c *C) {
+	ds := frenchPresidentDataset()
+	j, err := ds.CSVWithOptions(tablib.CSVOptions{Delimiter: ';', Quote: tablib.QuoteNonNumeric,
+		UseCRLF: true, WriteBOM: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(j.String(), Equals, "\xEF\xBB\xBF"+`"firstName";"lastName";"gpa"`+"\r\n"+
+		`"Jacques";"Chirac";88`+"\r\n"+
+		`"Nicolas";"Sarkozy";98`+"\r\n"+
+		`"François";"Hollande";34`+"\r\n")
+
+	j, err = ds.CSVWithOptions(tablib.CSVOptions{Delimiter: '|', Quote: tablib.QuoteAll, NoHeader: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(j.String(), Equals, `"Jacques"|"Chirac"|"88"
+"Nicolas"|"Sarkozy"|"98"
+"François"|"Hollande"|"34"
+`)
+
+	ds.AppendValues("Charles", "de Gaulle, Général", 100)
+	_, err = ds.CSVWithOptions(tablib.CSVOptions{Quote: tablib.QuoteNone})
+	c.Assert(err, Equals, tablib.ErrUnquotableField)
+
+	var b bytes.Buffer
+	w := tablib.NewCSVWriterWithOptions(&b, []string{"name", "value"}, tablib.CSVOptions{Quote: tablib.QuoteNone})
+	c.Assert(w.AppendValues("ok", "1"), Equals, nil)
+	c.Assert(w.AppendValues("no", "a,b"), Equals, tablib.ErrUnquotableField)
+	c.Assert(w.Flush(), Equals, nil)
+	c.Assert(b.String(), Equals, "name,value\nok,1\n")
+
+	nan := tablib.NewDataset([]string{"value"})
+	nan.AppendValues("NaN")
+	nan.AppendValues("Inf")
+	nan.AppendValues("1.5")
+	j, err = nan.CSVWithOptions(tablib.CSVOptions{Quote: tablib.QuoteNonNumeric, NoHeader: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(j.String(), Equals, "\"NaN\"\n\"Inf\"\n1.5\n")
+}
+
+func (s *TablibSuite) TestLoadCSVWithOptions(c *C) {
+	input := "\xEF\xBB\xBF# exported from the mainframe\r\n" +
+		"Bentley; Continental GT; 2003\r\n" +
+		"Ferrari; 458; 2009\r\n"
+	ds, err := tablib.LoadCSVWithOptions([]byte(input), tablib.CSVOptions{Delimiter: ';',
+		Comment: '#', TrimLeadingSpace: true, NoHeader: true, Headers: []string{"Maker", "Model", "Year"}})
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Height(), Equals, 2)
+	c.Assert(ds.Headers(), DeepEquals, []string{"Maker", "Model", "Year"})
+	r, _ := ds.Row(1)
+	c.Assert(r["Model"], Equals, "458")
+
+	ds, err = tablib.LoadCSVWithOptions([]byte("a|b\n1|2\n"), tablib.CSVOptions{Delimiter: '|', NoHeader: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"Column 1", "Column 2"})
+	c.Assert(ds.Height(), Equals, 2)
+}
+
+func (s *TablibSuite) TestSniffCSV(c *C) {
+	opts := tablib.SniffCSV([]byte("\xEF\xBB\xBFMaker;Model;Year\r\nBentley;\"Continental; GT\";2003\r\nFerrari;458;2009\r\n"))
+	c.Assert(opts.Delimiter, Equals, ';')
+	c.Assert(opts.WriteBOM, Equals, true)
+	c.Assert(opts.UseCRLF, Equals, true)
+	c.Assert(opts.NoHeader, Equals, false)
+
+	opts = tablib.SniffCSV([]byte("# dump\nBentley| 2003| 1.5\nFerrari| 2009| 2.5\nSkoda| 2011"))
+	c.Assert(opts.Delimiter, Equals, '|')
+	c.Assert(opts.Comment, Equals, '#')
+	c.Assert(opts.TrimLeadingSpace, Equals, true)
+	c.Assert(opts.NoHeader, Equals, true)
+}
+
 func (s *TablibSuite) TestHTML(c *C) {
 	ds := frenchPresidentDataset()
 	j := ds.HTML()
@@ -532,39 +1172,787 @@ func (s *TablibSuite) TestTabular(c *C) {
 func (s *TablibSuite) TestMySQL(c *C) {
 	ds := frenchPresidentDataset()
 	j := ds.MySQL("presidents")
-	c.Assert(j.String(), Equals, `CREATE TABLE IF NOT EXISTS presidents
+	c.Assert(j.String(), Equals, strings.Replace(`CREATE TABLE IF NOT EXISTS ~presidents~
 (
-	id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
-	firstName VARCHAR(9),
-	lastName VARCHAR(8),
-	gpa DOUBLE
+	~id~ INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	~firstName~ VARCHAR(9),
+	~lastName~ VARCHAR(8),
+	~gpa~ DOUBLE
 );
 
-INSERT INTO presidents VALUES(1, 'Jacques', 'Chirac', 88);
-INSERT INTO presidents VALUES(2, 'Nicolas', 'Sarkozy', 98);
-INSERT INTO presidents VALUES(3, 'François', 'Hollande', 34);
+BEGIN;
+INSERT INTO ~presidents~ (~firstName~, ~lastName~, ~gpa~) VALUES('Jacques', 'Chirac', 88);
+INSERT INTO ~presidents~ (~firstName~, ~lastName~, ~gpa~) VALUES('Nicolas', 'Sarkozy', 98);
+INSERT INTO ~presidents~ (~firstName~, ~lastName~, ~gpa~) VALUES('François', 'Hollande', 34);
 
 COMMIT;
-`)
+`, "~", "`", -1))
 }
 
 func (s *TablibSuite) TestPostgres(c *C) {
 	ds := frenchPresidentDataset()
 	j := ds.Postgres("presidents")
-	c.Assert(j.String(), Equals, `CREATE TABLE IF NOT EXISTS presidents
+	c.Assert(j.String(), Equals, `CREATE TABLE IF NOT EXISTS "presidents"
+(
+	"id" SERIAL PRIMARY KEY,
+	"firstName" TEXT,
+	"lastName" TEXT,
+	"gpa" NUMERIC
+);
+
+BEGIN;
+INSERT INTO "presidents" ("firstName", "lastName", "gpa") VALUES('Jacques', 'Chirac', 88);
+INSERT INTO "presidents" ("firstName", "lastName", "gpa") VALUES('Nicolas', 'Sarkozy', 98);
+INSERT INTO "presidents" ("firstName", "lastName", "gpa") VALUES('François', 'Hollande', 34);
+
+COMMIT;
+`)
+}
+
+func (s *TablibSuite) TestSQLDialects(c *C) {
+	c.Assert(tablib.DialectMySQL.QuoteIdentifier("ord`er"), Equals, "`ord``er`")
+	c.Assert(tablib.DialectPostgres.QuoteIdentifier(`ord"er`), Equals, `"ord""er"`)
+	c.Assert(tablib.DialectSQLite.QuoteIdentifier("order"), Equals, `"order"`)
+	c.Assert(tablib.DialectSQLServer.QuoteIdentifier("ord]er"), Equals, "[ord]]er]")
+	c.Assert(tablib.DialectMySQL.QuoteString(`it's C:\`), Equals, `'it''s C:\\'`)
+	c.Assert(tablib.DialectPostgres.QuoteString(`it's C:\`), Equals, `'it''s C:\'`)
+	c.Assert(tablib.DialectSQLServer.QuoteString("it's"), Equals, "N'it''s'")
+
+	born := time.Date(1990, 5, 1, 10, 30, 0, 0, time.FixedZone("", 2*3600))
+	ds := tablib.NewDataset([]string{"order", "note", "born", "vip"})
+	ds.AppendValues(1, `a\'b`, born, true)
+	ds.AppendValues(2.5, nil, nil, false)
+	ds.SetSchema(tablib.Schema{{Name: "vip", Type: tablib.ColumnTypeBool}})
+
+	c.Assert(ds.MySQL("t").String(), Equals, strings.Replace(`CREATE TABLE IF NOT EXISTS ~t~
+(
+	~id~ INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	~order~ DOUBLE,
+	~note~ VARCHAR(4),
+	~born~ TIMESTAMP,
+	~vip~ BOOLEAN NOT NULL
+);
+
+BEGIN;
+INSERT INTO ~t~ (~order~, ~note~, ~born~, ~vip~) VALUES(1, 'a\\''b', CONVERT_TZ('1990-05-01 10:30:00', '+02:00', @@session.time_zone), TRUE);
+INSERT INTO ~t~ (~order~, ~note~, ~born~, ~vip~) VALUES(2.5, NULL, NULL, FALSE);
+
+COMMIT;
+`, "~", "`", -1))
+	c.Assert(ds.SQLite("t").String(), Equals, `CREATE TABLE IF NOT EXISTS "t"
+(
+	"id" INTEGER PRIMARY KEY,
+	"order" REAL,
+	"note" TEXT,
+	"born" TEXT,
+	"vip" INTEGER NOT NULL
+);
+
+BEGIN;
+INSERT INTO "t" ("order", "note", "born", "vip") VALUES(1, 'a\''b', '1990-05-01T10:30:00+02:00', 1);
+INSERT INTO "t" ("order", "note", "born", "vip") VALUES(2.5, NULL, NULL, 0);
+
+COMMIT;
+`)
+	c.Assert(ds.SQLServer("t").String(), Equals, `IF OBJECT_ID(N't', N'U') IS NULL
+CREATE TABLE [t]
+(
+	[id] INT IDENTITY(1,1) PRIMARY KEY,
+	[order] FLOAT,
+	[note] NVARCHAR(4),
+	[born] DATETIMEOFFSET,
+	[vip] BIT NOT NULL
+);
+
+BEGIN TRANSACTION;
+INSERT INTO [t] ([order], [note], [born], [vip]) VALUES(1, N'a\''b', '1990-05-01 10:30:00+02:00', 1);
+INSERT INTO [t] ([order], [note], [born], [vip]) VALUES(2.5, NULL, NULL, 0);
+
+COMMIT;
+`)
+
+	statement, args := ds.PreparedInsert("t", tablib.DialectPostgres)
+	c.Assert(statement, Equals, `INSERT INTO "t" ("order", "note", "born", "vip") VALUES ($1, $2, $3, $4)`)
+	c.Assert(args, DeepEquals, [][]interface{}{{1, `a\'b`, born, true}, {2.5, nil, nil, false}})
+	statement, _ = ds.PreparedInsert("t", tablib.DialectSQLServer)
+	c.Assert(statement, Equals, "INSERT INTO [t] ([order], [note], [born], [vip]) VALUES (@p1, @p2, @p3, @p4)")
+	statement, _ = ds.PreparedInsert("t", tablib.DialectMySQL)
+	c.Assert(statement, Equals, "INSERT INTO `t` (`order`, `note`, `born`, `vip`) VALUES (?, ?, ?, ?)")
+
+	// booleans are not numbers
+	mixed := tablib.NewDataset([]string{"flag", "ok"})
+	mixed.AppendValues(1, true)
+	mixed.AppendValues(true, false)
+	c.Assert(mixed.Postgres("m").String(), Equals, `CREATE TABLE IF NOT EXISTS "m"
+(
+	"id" SERIAL PRIMARY KEY,
+	"flag" TEXT,
+	"ok" BOOLEAN
+);
+
+BEGIN;
+INSERT INTO "m" ("flag", "ok") VALUES('1', TRUE);
+INSERT INTO "m" ("flag", "ok") VALUES('true', FALSE);
+
+COMMIT;
+`)
+
+	// times keep their instant and nanoseconds, with or without COPY
+	at := time.Date(2020, 1, 1, 10, 0, 0, 123456789, time.FixedZone("", 2*3600))
+	times := tablib.NewDatasetWithSchema(tablib.Schema{{Name: "at", Type: tablib.ColumnTypeTime, Default: at}})
+	times.AppendValues(at)
+	c.Assert(times.SQL("ts", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly}).String(),
+		Equals, "BEGIN;\nINSERT INTO \"ts\" (\"at\") VALUES('2020-01-01T10:00:00.123456789+02:00');\n\nCOMMIT;\n")
+	c.Assert(times.SQL("ts", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly, Copy: true}).String(),
+		Equals, "BEGIN;\nCOPY \"ts\" (\"at\") FROM STDIN;\n2020-01-01T10:00:00.123456789+02:00\n\\.\n\nCOMMIT;\n")
+	c.Assert(times.SQL("ts", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLSchemaOnly}).String(),
+		Matches, `(?s).*"at" TIMESTAMPTZ NOT NULL DEFAULT '2020-01-01T10:00:00.123456789\+02:00'.*`)
+	c.Assert(times.MySQL("ts").String(), Equals, strings.Replace(`CREATE TABLE IF NOT EXISTS ~ts~
+(
+	~id~ INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	~at~ TIMESTAMP NOT NULL DEFAULT (CONVERT_TZ('2020-01-01 10:00:00.123456', '+02:00', @@session.time_zone))
+);
+
+BEGIN;
+INSERT INTO ~ts~ (~at~) VALUES(CONVERT_TZ('2020-01-01 10:00:00.123456', '+02:00', @@session.time_zone));
+
+COMMIT;
+`, "~", "`", -1))
+}
+
+func (s *TablibSuite) TestSQLOptions(c *C) {
+	ds := tablib.NewDataset([]string{"code", "name", "ok"})
+	ds.AppendValues("FR", "France", true)
+	ds.AppendValues("US", "United\tStates", false)
+	ds.AppendValues("XX", nil, true)
+	ds.SetPrimaryKey("code")
+	ds.SetSchema(tablib.Schema{{Name: "ok", Type: tablib.ColumnTypeBool}})
+
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectSQLite, Content: tablib.SQLSchemaOnly}).String(),
+		Equals, `CREATE TABLE IF NOT EXISTS "c"
+(
+	"code" TEXT,
+	"name" TEXT,
+	"ok" INTEGER NOT NULL,
+	PRIMARY KEY ("code")
+);
+
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly, BatchSize: 2}).String(),
+		Equals, `BEGIN;
+INSERT INTO "c" ("code", "name", "ok") VALUES
+	('FR', 'France', TRUE),
+	('US', 'United	States', FALSE);
+INSERT INTO "c" ("code", "name", "ok") VALUES('XX', NULL, TRUE);
+
+COMMIT;
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly,
+		BatchSize: 3, UpsertKeys: []string{"code"}}).String(), Equals, `BEGIN;
+INSERT INTO "c" ("code", "name", "ok") VALUES
+	('FR', 'France', TRUE),
+	('US', 'United	States', FALSE),
+	('XX', NULL, TRUE)
+ON CONFLICT ("code") DO UPDATE SET "name" = EXCLUDED."name", "ok" = EXCLUDED."ok";
+
+COMMIT;
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectMySQL, Content: tablib.SQLDataOnly,
+		BatchSize: 3, UpsertKeys: []string{"code"}}).String(), Equals, strings.Replace(`BEGIN;
+INSERT INTO ~c~ (~code~, ~name~, ~ok~) VALUES
+	('FR', 'France', TRUE),
+	('US', 'United	States', FALSE),
+	('XX', NULL, TRUE)
+ON DUPLICATE KEY UPDATE ~name~ = VALUES(~name~), ~ok~ = VALUES(~ok~);
+
+COMMIT;
+`, "~", "`", -1))
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectSQLServer, Content: tablib.SQLDataOnly,
+		BatchSize: 2, UpsertKeys: []string{"code"}}).String(), Equals, `BEGIN TRANSACTION;
+MERGE INTO [c] AS target
+USING (VALUES
+	(N'FR', N'France', 1),
+	(N'US', N'United	States', 0)) AS source ([code], [name], [ok])
+ON target.[code] = source.[code]
+WHEN MATCHED THEN UPDATE SET [name] = source.[name], [ok] = source.[ok]
+WHEN NOT MATCHED THEN INSERT ([code], [name], [ok]) VALUES (source.[code], source.[name], source.[ok]);
+MERGE INTO [c] AS target
+USING (VALUES
+	(N'XX', NULL, 1)) AS source ([code], [name], [ok])
+ON target.[code] = source.[code]
+WHEN MATCHED THEN UPDATE SET [name] = source.[name], [ok] = source.[ok]
+WHEN NOT MATCHED THEN INSERT ([code], [name], [ok]) VALUES (source.[code], source.[name], source.[ok]);
+
+COMMIT;
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectSQLite, Content: tablib.SQLDataOnly,
+		UpsertKeys: []string{"code", "name", "ok"}}).String(), Equals, `BEGIN;
+INSERT INTO "c" ("code", "name", "ok") VALUES('FR', 'France', 1)
+ON CONFLICT ("code", "name", "ok") DO NOTHING;
+INSERT INTO "c" ("code", "name", "ok") VALUES('US', 'United	States', 0)
+ON CONFLICT ("code", "name", "ok") DO NOTHING;
+INSERT INTO "c" ("code", "name", "ok") VALUES('XX', NULL, 1)
+ON CONFLICT ("code", "name", "ok") DO NOTHING;
+
+COMMIT;
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly, Copy: true}).String(),
+		Equals, `BEGIN;
+COPY "c" ("code", "name", "ok") FROM STDIN;
+FR	France	true
+US	United\tStates	false
+XX	\N	true
+\.
+
+COMMIT;
+`)
+
+	// unknown upsert keys are ignored, and so is Copy out of Postgres
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectSQLite, Content: tablib.SQLDataOnly,
+		BatchSize: 3, UpsertKeys: []string{"code", "iso"}}).String(), Equals, `BEGIN;
+INSERT INTO "c" ("code", "name", "ok") VALUES
+	('FR', 'France', 1),
+	('US', 'United	States', 0),
+	('XX', NULL, 1)
+ON CONFLICT ("code") DO UPDATE SET "name" = EXCLUDED."name", "ok" = EXCLUDED."ok";
+
+COMMIT;
+`)
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectMySQL, Content: tablib.SQLDataOnly,
+		BatchSize: 3, UpsertKeys: []string{"iso"}, Copy: true}).String(), Equals, strings.Replace(`BEGIN;
+INSERT INTO ~c~ (~code~, ~name~, ~ok~) VALUES
+	('FR', 'France', TRUE),
+	('US', 'United	States', FALSE),
+	('XX', NULL, TRUE);
+
+COMMIT;
+`, "~", "`", -1))
+
+	// the synthetic id is left to the database
+	ds.SetPrimaryKey()
+	c.Assert(ds.SQL("c", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLDataOnly, Copy: true}).String(),
+		Equals, `BEGIN;
+COPY "c" ("code", "name", "ok") FROM STDIN;
+FR	France	true
+US	United\tStates	false
+XX	\N	true
+\.
+
+COMMIT;
+`)
+}
+
+func (s *TablibSuite) TestKeys(c *C) {
+	countries := tablib.NewDataset([]string{"code", "name"})
+	countries.AppendValues("FR", "France")
+	countries.AppendValues("US", "United States")
+	countries.AppendValues("FR", "Francia")
+	c.Assert(countries.SetPrimaryKey("id"), Equals, tablib.ErrInvalidColumnIndex)
+	c.Assert(countries.SetPrimaryKey("code"), Equals, nil)
+	c.Assert(countries.AddUniqueKey("name"), Equals, nil)
+	c.Assert(countries.HasAnyConstraint(), Equals, true)
+	c.Assert(countries.Valid(), Equals, false)
+	c.Assert(countries.ValidationErrors, HasLen, 1)
+	e := countries.ValidationErrors[0]
+	c.Assert(e.Row, Equals, 2)
+	c.Assert(e.Constraint, Equals, "primary_key")
+	c.Assert(e.Value, DeepEquals, tablib.Row{"code": "FR"})
+	countries.DeleteRow(2)
+
+	people := tablib.NewDataset([]string{"firstName", "lastName", "country"})
+	people.AppendValues("Jacques", "Chirac", "FR")
+	people.AppendValues("George", "Bush", "US")
+	people.AppendValues("Angela", "Merkel", "DE")
+	people.AppendValues("Nobody", nil, nil)
+	people.SetPrimaryKey("firstName", "lastName")
+	c.Assert(people.AddForeignKey([]string{"country"}, "countries", []string{"code", "name"}),
+		Equals, tablib.ErrInvalidDimensions)
+	c.Assert(people.AddForeignKey([]string{"country"}, "countries", []string{"code"}), Equals, nil)
+	c.Assert(people.ForeignKeys(), DeepEquals,
+		[]tablib.ForeignKey{{[]string{"country"}, "countries", []string{"code"}}})
+
+	db := tablib.NewDatabook()
+	db.AddSheet("countries", countries)
+	db.AddSheet("people", people)
+	c.Assert(db.Valid(), Equals, false)
+	c.Assert(countries.ValidationErrors, HasLen, 0)
+	c.Assert(people.ValidationErrors, HasLen, 2)
+	c.Assert(people.ValidationErrors[0].Row, Equals, 2)
+	c.Assert(people.ValidationErrors[0].Error(), Equals,
+		`tablib: row 2, columns ["country"]: must reference an existing countries(code) (foreign_key)`)
+	c.Assert(people.ValidationErrors[1].Row, Equals, 3)
+	c.Assert(people.ValidationErrors[1].Constraint, Equals, "primary_key")
+
+	people.DeleteRow(3)
+	people.DeleteRow(2)
+	c.Assert(db.Valid(), Equals, true)
+
+	// foreign keys to other tables are ignored, unknown columns are reported
+	others := tablib.NewDataset([]string{"country"})
+	others.AppendValues("FR")
+	others.AddForeignKey([]string{"country"}, "elsewhere", []string{"code"})
+	ob := tablib.NewDatabook()
+	ob.AddSheet("countries", countries)
+	ob.AddSheet("others", others)
+	c.Assert(ob.Valid(), Equals, true)
+	others.AddForeignKey([]string{"country"}, "countries", []string{"iso"})
+	c.Assert(ob.Valid(), Equals, false)
+	c.Assert(others.ValidationErrors, HasLen, 1)
+	c.Assert(others.ValidationErrors[0].Error(), Equals,
+		`tablib: columns ["country"]: must reference existing columns countries(iso) (foreign_key)`)
+
+	// keys are not shared by copies
+	copied := people.Where(func(tablib.Row) bool { return true })
+	copied.AddForeignKey([]string{"lastName"}, "names", []string{"name"})
+	copied.ForeignKeys()[0].Columns[0] = "lastName"
+	c.Assert(people.ForeignKeys(), HasLen, 1)
+	c.Assert(people.ForeignKeys()[0].Columns, DeepEquals, []string{"country"})
+
+	c.Assert(people.Postgres("people").String(), Equals, `CREATE TABLE IF NOT EXISTS "people"
+(
+	"firstName" TEXT,
+	"lastName" TEXT,
+	"country" TEXT,
+	PRIMARY KEY ("firstName", "lastName"),
+	FOREIGN KEY ("country") REFERENCES "countries" ("code")
+);
+
+BEGIN;
+INSERT INTO "people" ("firstName", "lastName", "country") VALUES('Jacques', 'Chirac', 'FR');
+INSERT INTO "people" ("firstName", "lastName", "country") VALUES('George', 'Bush', 'US');
+
+COMMIT;
+`)
+	countries.DeleteColumn("code")
+	c.Assert(countries.PrimaryKey(), IsNil)
+	c.Assert(countries.SQLite("countries").String(), Equals, `CREATE TABLE IF NOT EXISTS "countries"
+(
+	"id" INTEGER PRIMARY KEY,
+	"name" TEXT,
+	UNIQUE ("name")
+);
+
+BEGIN;
+INSERT INTO "countries" ("name") VALUES('France');
+INSERT INTO "countries" ("name") VALUES('United States');
+
+COMMIT;
+`)
+
+	// the synthetic id does not clash with an id header
+	ids := tablib.NewDataset([]string{"id", "_id"})
+	ids.AppendValues(7, "x")
+	c.Assert(ids.SQL("ids", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLSchemaOnly}).String(),
+		Equals, `CREATE TABLE IF NOT EXISTS "ids"
+(
+	"__id" SERIAL PRIMARY KEY,
+	"id" NUMERIC,
+	"_id" TEXT
+);
+
+`)
+}
+
+func (s *TablibSuite) TestDatabookSQL(c *C) {
+	people := tablib.NewDataset([]string{"name", "country"})
+	people.AppendValues("Jacques", "FR")
+	people.AddForeignKey([]string{"country"}, "the countries", []string{"code"})
+	countries := tablib.NewDataset([]string{"code"})
+	countries.AppendValues("FR")
+	countries.SetPrimaryKey("code")
+
+	db := tablib.NewDatabook()
+	db.AddSheet("people", people)
+	db.AddSheet("the countries", countries)
+	db.AddSheet("2019", tablib.NewDataset([]string{"x"}))
+	db.AddSheet("the-countries", tablib.NewDataset([]string{"y"}))
+
+	c.Assert(db.Postgres().String(), Equals, `BEGIN;
+
+CREATE TABLE IF NOT EXISTS "the_countries"
+(
+	"code" TEXT,
+	PRIMARY KEY ("code")
+);
+
+CREATE TABLE IF NOT EXISTS "people"
+(
+	"id" SERIAL PRIMARY KEY,
+	"name" TEXT,
+	"country" TEXT,
+	FOREIGN KEY ("country") REFERENCES "the_countries" ("code")
+);
+
+CREATE TABLE IF NOT EXISTS "_2019"
+(
+	"id" SERIAL PRIMARY KEY,
+	"x" TEXT
+);
+
+CREATE TABLE IF NOT EXISTS "the_countries_2"
+(
+	"id" SERIAL PRIMARY KEY,
+	"y" TEXT
+);
+
+INSERT INTO "the_countries" ("code") VALUES('FR');
+
+INSERT INTO "people" ("name", "country") VALUES('Jacques', 'FR');
+
+COMMIT;
+`)
+	c.Assert(db.SQL(tablib.SQLOptions{Dialect: tablib.DialectMySQL, Content: tablib.SQLDataOnly}).String(),
+		Equals, strings.Replace(`BEGIN;
+
+INSERT INTO ~the_countries~ (~code~) VALUES('FR');
+
+INSERT INTO ~people~ (~name~, ~country~) VALUES('Jacques', 'FR');
+
+COMMIT;
+`, "~", "`", -1))
+
+	// references within a cycle are added once the tables exist
+	teams := tablib.NewDataset([]string{"team", "captain"})
+	teams.AppendValues("Blue", "Ann")
+	teams.SetPrimaryKey("team")
+	teams.AddForeignKey([]string{"captain"}, "Données", []string{"player"})
+	players := tablib.NewDataset([]string{"player", "team"})
+	players.AppendValues("Ann", "Blue")
+	players.SetPrimaryKey("player")
+	players.AddForeignKey([]string{"team"}, "teams", []string{"team"})
+	cycle := tablib.NewDatabook()
+	cycle.AddSheet("teams", teams)
+	cycle.AddSheet("Données", players)
+	c.Assert(cycle.SQL(tablib.SQLOptions{Dialect: tablib.DialectSQLServer}).String(), Equals, `BEGIN TRANSACTION;
+
+IF OBJECT_ID(N'Donn_es', N'U') IS NULL
+CREATE TABLE [Donn_es]
+(
+	[player] NVARCHAR(3),
+	[team] NVARCHAR(4),
+	PRIMARY KEY ([player])
+);
+
+IF OBJECT_ID(N'teams', N'U') IS NULL
+CREATE TABLE [teams]
+(
+	[team] NVARCHAR(4),
+	[captain] NVARCHAR(3),
+	PRIMARY KEY ([team]),
+	FOREIGN KEY ([captain]) REFERENCES [Donn_es] ([player])
+);
+
+INSERT INTO [Donn_es] ([player], [team]) VALUES(N'Ann', N'Blue');
+
+INSERT INTO [teams] ([team], [captain]) VALUES(N'Blue', N'Ann');
+
+ALTER TABLE [Donn_es] ADD FOREIGN KEY ([team]) REFERENCES [teams] ([team]);
+
+COMMIT;
+`)
+	c.Assert(cycle.SQL(tablib.SQLOptions{Dialect: tablib.DialectSQLite, Content: tablib.SQLSchemaOnly}).String(),
+		Equals, `BEGIN;
+
+CREATE TABLE IF NOT EXISTS "Donn_es"
+(
+	"player" TEXT,
+	"team" TEXT,
+	PRIMARY KEY ("player"),
+	FOREIGN KEY ("team") REFERENCES "teams" ("team")
+);
+
+CREATE TABLE IF NOT EXISTS "teams"
 (
-	id SERIAL PRIMARY KEY,
-	firstName TEXT,
-	lastName TEXT,
-	gpa NUMERIC
+	"team" TEXT,
+	"captain" TEXT,
+	PRIMARY KEY ("team"),
+	FOREIGN KEY ("captain") REFERENCES "Donn_es" ("player")
 );
 
-INSERT INTO presidents VALUES(1, 'Jacques', 'Chirac', 88);
-INSERT INTO presidents VALUES(2, 'Nicolas', 'Sarkozy', 98);
-INSERT INTO presidents VALUES(3, 'François', 'Hollande', 34);
+COMMIT;
+`)
+}
+
+func (s *TablibSuite) TestSchema(c *C) {
+	schema := tablib.Schema{
+		{Name: "rank", Type: tablib.ColumnTypeInt, Description: "ranking"},
+		{Name: "name", Type: tablib.ColumnTypeString, Nullable: true},
+		{Name: "score", Type: tablib.ColumnTypeFloat, Default: 0, Format: "0.00"},
+		{Name: "born", Type: tablib.ColumnTypeTime, Nullable: true},
+	}
+	ds := tablib.NewDatasetWithSchema(schema)
+	c.Assert(ds.Headers(), DeepEquals, []string{"rank", "name", "score", "born"})
+	c.Assert(ds.Schema(), DeepEquals, schema)
+	c.Assert(ds.ColumnFormat("score"), Equals, "0.00")
+
+	born := time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(ds.AppendValues(1, "John", 12, born), Equals, nil)
+	c.Assert(ds.AppendValues("2", 42, nil, "1990-05-01"), Equals, nil)
+	c.Assert(ds.InsertValues(0, 3.0, nil, "1.5", nil), Equals, nil)
+	c.Assert(ds.AppendValues(nil, "x", 1, nil), Equals, tablib.ErrNullValue)
+	c.Assert(ds.AppendValues("x", "x", 1, nil), Equals, tablib.ErrInvalidConversion)
+	c.Assert(ds.AppendValues(1.5, "x", 1, nil), Equals, tablib.ErrInvalidConversion)
+	c.Assert(ds.InsertTagged(0, []interface{}{4, "Bob", 1, nil}, "new"), Equals, nil)
+	c.Assert(ds.Height(), Equals, 4)
+	c.Assert(ds.Column("rank"), DeepEquals, []interface{}{int64(4), int64(3), int64(1), int64(2)})
+	c.Assert(ds.Column("name"), DeepEquals, []interface{}{"Bob", nil, "John", "42"})
+	c.Assert(ds.Column("score"), DeepEquals, []interface{}{1.0, 1.5, 12.0, 0.0})
+	c.Assert(ds.Column("born"), DeepEquals, []interface{}{nil, nil, born, born})
+
+	c.Assert(ds.Postgres("people").String(), Equals, `CREATE TABLE IF NOT EXISTS "people"
+(
+	"id" SERIAL PRIMARY KEY,
+	"rank" BIGINT NOT NULL,
+	"name" TEXT,
+	"score" NUMERIC NOT NULL DEFAULT 0,
+	"born" TIMESTAMPTZ
+);
+
+BEGIN;
+INSERT INTO "people" ("rank", "name", "score", "born") VALUES(4, 'Bob', 1, NULL);
+INSERT INTO "people" ("rank", "name", "score", "born") VALUES(3, NULL, 1.5, NULL);
+INSERT INTO "people" ("rank", "name", "score", "born") VALUES(1, 'John', 12, '1990-05-01T00:00:00Z');
+INSERT INTO "people" ("rank", "name", "score", "born") VALUES(2, '42', 0, '1990-05-01T00:00:00Z');
 
 COMMIT;
 `)
+
+	js, err := ds.JSONSchema()
+	c.Assert(err, Equals, nil)
+	c.Assert(js.String(), Equals, `{"$schema":"http://json-schema.org/draft-07/schema#","type":"array",`+
+		`"items":{"type":"object","properties":{"rank":{"description":"ranking","type":"integer"},`+
+		`"name":{"type":["string","null"]},"score":{"default":0,"type":"number"},`+
+		`"born":{"format":"date-time","type":["string","null"]}},"required":["rank","name","score","born"]}}`)
+
+	loaded, err := tablib.LoadCSVWithOptions([]byte("id,name\n1,John\n,Bob\n"),
+		tablib.CSVOptions{Schema: tablib.Schema{{Name: "id", Type: tablib.ColumnTypeInt, Nullable: true}}})
+	c.Assert(err, Equals, nil)
+	c.Assert(loaded.Column("id"), DeepEquals, []interface{}{int64(1), nil})
+	_, err = tablib.LoadJSONWithOptions([]byte(`[{"id": 1.5}]`),
+		tablib.LoadOptions{Schema: tablib.Schema{{Name: "id", Type: tablib.ColumnTypeInt}}})
+	c.Assert(err, Equals, tablib.ErrInvalidConversion)
+	c.Assert(loaded.SetSchema(tablib.Schema{{Name: "age"}}), Equals, tablib.ErrInvalidColumnIndex)
+	c.Assert(loaded.SetSchema(tablib.Schema{{Name: "name", Type: tablib.ColumnTypeBool}}), Equals, tablib.ErrInvalidConversion)
+	c.Assert(loaded.Column("name"), DeepEquals, []interface{}{"John", "Bob"})
+
+	// columns added and stacked rows are coerced too
+	typed := tablib.NewDatasetWithSchema(tablib.Schema{{Name: "n", Type: tablib.ColumnTypeInt}})
+	c.Assert(typed.AppendValues(1), Equals, nil)
+	c.Assert(typed.AppendColumn("n", []interface{}{"x"}), Equals, tablib.ErrInvalidConversion)
+	c.Assert(typed.InsertColumn(0, "n", []interface{}{nil}), Equals, tablib.ErrNullValue)
+	c.Assert(typed.Width(), Equals, 1)
+	c.Assert(typed.InsertColumn(0, "n", []interface{}{"2"}), Equals, nil)
+	c.Assert(typed.Column("n"), DeepEquals, []interface{}{int64(2)})
+	c.Assert(typed.DeleteColumn("n"), Equals, nil)
+	c.Assert(typed.SetSchema(tablib.Schema{{Name: "n", Type: tablib.ColumnTypeInt}}), Equals, nil)
+	untyped := tablib.NewDataset([]string{"n"})
+	untyped.AppendValues("3")
+	stacked, err := typed.Stack(untyped)
+	c.Assert(err, Equals, nil)
+	c.Assert(stacked.Schema(), DeepEquals, typed.Schema())
+	c.Assert(stacked.Column("n"), DeepEquals, []interface{}{int64(1), int64(3)})
+	untyped.AppendValues("x")
+	_, err = typed.Stack(untyped)
+	c.Assert(err, Equals, tablib.ErrInvalidConversion)
+	c.Assert(stacked.DeleteColumn("n"), Equals, nil)
+	c.Assert(typed.Schema(), HasLen, 1)
+}
+
+// fakeTable is a result set served by fakeDriver.
+type fakeTable struct {
+	columns []string
+	types   []reflect.Type
+	rows    [][]driver.Value
+}
+
+// fakeTables are the result sets served by fakeDriver, by query.
+var fakeTables = map[string]fakeTable{
+	"SELECT * FROM people": {
+		[]string{"id", "name", "score", "note"},
+		[]reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf(sql.NullString{}), reflect.TypeOf(float64(0)), nil},
+		[][]driver.Value{
+			{int64(1), "John", 12.5, []byte("first")},
+			{int64(2), nil, nil, nil},
+			{int64(3), "Bob", 3.0, []byte("last")},
+		},
+	},
+}
+
+func init() {
+	sql.Register("tablib-fake", fakeDriver{})
+}
+
+// fakeExecuted are the statements executed through fakeDriver, with their
+// arguments, once their transaction is committed.
+var fakeExecuted []string
+
+// fakeDriver is an in-memory database/sql driver serving fakeTables and
+// recording the executed statements in fakeExecuted. The statements having
+// "fail" as argument fail.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConn struct {
+	inTx    bool
+	pending []string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx, c.pending = true, nil
+	return c, nil
+}
+func (c *fakeConn) Commit() error {
+	fakeExecuted = append(fakeExecuted, c.pending...)
+	c.inTx = false
+	return nil
+}
+func (c *fakeConn) Rollback() error {
+	c.inTx = false
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	executed := s.query
+	if len(args) > 0 {
+		executed += " " + fmt.Sprint(args)
+	}
+	for _, arg := range args {
+		if arg == "fail" {
+			return nil, errors.New("constraint failed")
+		}
+	}
+	if s.conn.inTx {
+		s.conn.pending = append(s.conn.pending, executed)
+	} else {
+		fakeExecuted = append(fakeExecuted, executed)
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	t, ok := fakeTables[s.query]
+	if !ok {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &fakeRows{t, 0}, nil
+}
+
+type fakeRows struct {
+	table fakeTable
+	pos   int
+}
+
+func (r *fakeRows) Columns() []string { return r.table.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos == len(r.table.rows) {
+		return io.EOF
+	}
+	copy(dest, r.table.rows[r.pos])
+	r.pos++
+	return nil
+}
+func (r *fakeRows) ColumnTypeScanType(i int) reflect.Type {
+	if t := r.table.types[i]; t != nil {
+		return t
+	}
+	return reflect.TypeOf(sql.RawBytes{})
+}
+
+func (s *TablibSuite) TestQueryDataset(c *C) {
+	db, err := sql.Open("tablib-fake", "")
+	c.Assert(err, Equals, nil)
+	defer db.Close()
+
+	ds, err := tablib.QueryDataset(context.Background(), db, "SELECT * FROM people")
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"id", "name", "score", "note"})
+	c.Assert(ds.Height(), Equals, 3)
+	c.Assert(ds.Column("id"), DeepEquals, []interface{}{int64(1), int64(2), int64(3)})
+	c.Assert(ds.Column("name"), DeepEquals, []interface{}{"John", nil, "Bob"})
+	c.Assert(ds.Column("score"), DeepEquals, []interface{}{12.5, nil, 3.0})
+	c.Assert(ds.Column("note"), DeepEquals, []interface{}{"first", nil, "last"})
+
+	_, err = tablib.QueryDataset(context.Background(), db, "SELECT * FROM unknown")
+	c.Assert(err, Equals, io.ErrUnexpectedEOF)
+
+	rows, err := db.Query("SELECT * FROM people")
+	c.Assert(err, Equals, nil)
+	ds, err = tablib.LoadSQLRowsWithOptions(context.Background(), rows, tablib.SQLLoadOptions{Limit: 2})
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Column("id"), DeepEquals, []interface{}{int64(1), int64(2)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rows, err = db.Query("SELECT * FROM people")
+	c.Assert(err, Equals, nil)
+	_, err = tablib.LoadSQLRowsWithOptions(ctx, rows, tablib.SQLLoadOptions{})
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *TablibSuite) TestInsertInto(c *C) {
+	db, err := sql.Open("tablib-fake", "")
+	c.Assert(err, Equals, nil)
+	defer db.Close()
+
+	ds := tablib.NewDataset([]string{"code", "name"})
+	ds.AppendValues("FR", "France")
+	ds.AppendValues("XX", "fail")
+	ds.AppendValues("US", "United States")
+	ds.AppendValues("YY", nil)
+	ds.AddConstraints("name", tablib.NotNull())
+
+	fakeExecuted = nil
+	result, err := ds.InsertInto(context.Background(), db, "countries",
+		tablib.InsertOptions{Dialect: tablib.DialectPostgres, CreateTable: true, BatchSize: 2, SkipInvalid: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(result.Inserted, Equals, 2)
+	c.Assert(result.Skipped, DeepEquals, []int{3})
+	c.Assert(result.Errors, HasLen, 1)
+	c.Assert(result.Errors[0].Error(), Equals, "tablib: row 1: constraint failed")
+	tableSQL := ds.SQL("countries", tablib.SQLOptions{Dialect: tablib.DialectPostgres, Content: tablib.SQLSchemaOnly}).String()
+	c.Assert(fakeExecuted, DeepEquals, []string{
+		tableSQL,
+		`INSERT INTO "countries" ("code", "name") VALUES ($1, $2) [FR France]`,
+		`INSERT INTO "countries" ("code", "name") VALUES ($1, $2) [US United States]`,
+	})
+
+	// the same rows can be inserted again, the ids being generated
+	fakeExecuted = nil
+	result, err = ds.InsertInto(context.Background(), db, "countries",
+		tablib.InsertOptions{Dialect: tablib.DialectSQLServer, CreateTable: true, SkipInvalid: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(result.Inserted, Equals, 2)
+	c.Assert(fakeExecuted[0], Matches, `(?s).*\[id\] INT IDENTITY\(1,1\) PRIMARY KEY.*`)
+	c.Assert(fakeExecuted[1:], DeepEquals, []string{
+		"INSERT INTO [countries] ([code], [name]) VALUES (@p1, @p2) [FR France]",
+		"INSERT INTO [countries] ([code], [name]) VALUES (@p1, @p2) [US United States]",
+	})
+
+	fakeExecuted = nil
+	result, err = ds.InsertInto(context.Background(), db, "countries", tablib.InsertOptions{Dialect: tablib.DialectMySQL})
+	c.Assert(err, Equals, nil)
+	c.Assert(result.Inserted, Equals, 3)
+	c.Assert(result.Skipped, HasLen, 0)
+	c.Assert(fakeExecuted, DeepEquals, []string{
+		"INSERT INTO `countries` (`code`, `name`) VALUES (?, ?) [FR France]",
+		"INSERT INTO `countries` (`code`, `name`) VALUES (?, ?) [US United States]",
+		"INSERT INTO `countries` (`code`, `name`) VALUES (?, ?) [YY <nil>]",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ds.InsertInto(ctx, db, "countries", tablib.InsertOptions{})
+	c.Assert(err, Equals, context.Canceled)
 }
 
 func (s *TablibSuite) TestLoadDatabookJSON(c *C) {
@@ -649,6 +2037,34 @@ func (s *TablibSuite) TestLoadDatabookYAML(c *C) {
 	c.Assert(db.Size(), Equals, 0)
 }
 
+func (s *TablibSuite) TestDatabookSheetOrder(c *C) {
+	db := tablib.NewDatabook()
+	db.AddSheet("Presidents", presidentDataset())
+	db.AddSheet("Cars", carDataset())
+	db.AddSheet("French", frenchPresidentDataset())
+	c.Assert(db.SheetTitles(), DeepEquals, []string{"Presidents", "Cars", "French"})
+
+	c.Assert(db.RenameSheet("French", "Cars"), Equals, tablib.ErrInvalidSheet)
+	c.Assert(db.RenameSheet("French", "Elysee"), Equals, nil)
+	c.Assert(db.MoveSheet("Elysee", 0), Equals, nil)
+	c.Assert(db.MoveSheet("Elysee", 3), Equals, tablib.ErrInvalidSheet)
+	c.Assert(db.SheetTitles(), DeepEquals, []string{"Elysee", "Presidents", "Cars"})
+	c.Assert(db.RemoveSheet("Presidents"), Equals, nil)
+	c.Assert(db.RemoveSheet("Presidents"), Equals, tablib.ErrInvalidSheet)
+	c.Assert(db.Size(), Equals, 2)
+
+	sheet, err := db.SheetAt(1)
+	c.Assert(err, Equals, nil)
+	c.Assert(sheet.Title(), Equals, "Cars")
+	_, err = db.SheetAt(2)
+	c.Assert(err, Equals, tablib.ErrInvalidSheet)
+
+	y, _ := db.YAML()
+	c.Assert(y.String()[:30], Equals, "- data:\n  - firstName: Jacques")
+	j, _ := db.JSON()
+	c.Assert(j.String()[:20], Equals, `[{"title": "Elysee",`)
+}
+
 func (s *TablibSuite) TestLoadCSV(c *C) {
 	var b bytes.Buffer
 	b.WriteString(`Maker, Model, Year
@@ -679,6 +2095,115 @@ Citroen` + "\t" + `Picasso II` + "\t" + `2013`)
 	c.Assert(r["Maker"], Equals, "Bentley")
 }
 
+func (s *TablibSuite) TestLoadCSVWithInference(c *C) {
+	ds, report, err := tablib.LoadCSVWithInference([]byte(`Maker,Year,Price,Electric,Released,Code
+Porsche,2012,89000.5,false,2012-05-01,007
+Tesla,2015,,true,2015-09-29T10:00:00Z,042`), tablib.InferOptions{
+		Overrides: map[string]tablib.ColumnType{"Code": tablib.ColumnTypeString},
+	})
+	c.Assert(err, Equals, nil)
+	c.Assert(report, DeepEquals, []tablib.ColumnInference{
+		{Header: "Maker", Type: tablib.ColumnTypeString},
+		{Header: "Year", Type: tablib.ColumnTypeInt},
+		{Header: "Price", Type: tablib.ColumnTypeFloat},
+		{Header: "Electric", Type: tablib.ColumnTypeBool},
+		{Header: "Released", Type: tablib.ColumnTypeTime},
+		{Header: "Code", Type: tablib.ColumnTypeString, Overridden: true},
+	})
+	r, _ := ds.Row(1)
+	c.Assert(r["Year"], Equals, int64(2015))
+	c.Assert(r["Price"], Equals, nil)
+	c.Assert(r["Electric"], Equals, true)
+	c.Assert(r["Released"], Equals, time.Date(2015, 9, 29, 10, 0, 0, 0, time.UTC))
+	c.Assert(r["Code"], Equals, "042")
+
+	_, _, err = tablib.LoadCSVWithInference([]byte("Maker\nPorsche"), tablib.InferOptions{
+		Overrides: map[string]tablib.ColumnType{"Maker": tablib.ColumnTypeInt},
+	})
+	c.Assert(err, Equals, tablib.ErrInvalidConversion)
+
+	ds, _ = tablib.LoadCSV([]byte("Year,Code\n2012,1\n2015,x"))
+	_, err = ds.InferTypes(tablib.InferOptions{
+		Overrides: map[string]tablib.ColumnType{"Code": tablib.ColumnTypeInt},
+	})
+	c.Assert(err, Equals, tablib.ErrInvalidConversion)
+	c.Assert(ds.Column("Year"), DeepEquals, []interface{}{"2012", "2015"})
+	c.Assert(ds.Column("Code"), DeepEquals, []interface{}{"1", "x"})
+
+	// leading zeros, NaN and infinities are not inferred as numbers
+	ds, report, err = tablib.LoadCSVWithInference([]byte("Zip,Ratio,Score,Level,Rate\n"+
+		"01234,NaN,Inf,0,0.5\n75001,1.5,Infinity,10,-00.5"), tablib.InferOptions{})
+	c.Assert(err, Equals, nil)
+	c.Assert(report, DeepEquals, []tablib.ColumnInference{
+		{Header: "Zip", Type: tablib.ColumnTypeString},
+		{Header: "Ratio", Type: tablib.ColumnTypeString},
+		{Header: "Score", Type: tablib.ColumnTypeString},
+		{Header: "Level", Type: tablib.ColumnTypeInt},
+		{Header: "Rate", Type: tablib.ColumnTypeString},
+	})
+	c.Assert(ds.Column("Zip"), DeepEquals, []interface{}{"01234", "75001"})
+
+	// overriding a column not holding strings fails
+	_, err = ds.InferTypes(tablib.InferOptions{})
+	c.Assert(err, Equals, nil)
+	_, err = ds.InferTypes(tablib.InferOptions{
+		Overrides: map[string]tablib.ColumnType{"Level": tablib.ColumnTypeFloat},
+	})
+	c.Assert(err, Equals, tablib.ErrInvalidConversion)
+	c.Assert(ds.Column("Level"), DeepEquals, []interface{}{int64(0), int64(10)})
+}
+
+func (s *TablibSuite) TestCSVReader(c *C) {
+	r, err := tablib.NewCSVReader(bytes.NewBufferString(`Maker,Model,Year
+Porsche,991,2012
+Skoda,Octavia,2011
+Ferrari,458,2009`))
+	c.Assert(err, Equals, nil)
+	r.AppendDynamicColumn("Car", func(row []interface{}) interface{} {
+		return row[0].(string) + " " + row[1].(string)
+	})
+	r.ConstrainColumn("Maker", func(val interface{}) bool { return val.(string) != "Skoda" })
+
+	c.Assert(r.Next(), Equals, true)
+	c.Assert(r.Row(), DeepEquals, []interface{}{"Porsche", "991", "2012", "Porsche 991"})
+	c.Assert(r.Valid(), Equals, true)
+	c.Assert(r.Next(), Equals, true)
+	c.Assert(r.Valid(), Equals, false)
+
+	_, err = r.NextBatch(0)
+	c.Assert(err, Equals, tablib.ErrInvalidBatchSize)
+	batch, err := r.NextBatch(10)
+	c.Assert(err, Equals, nil)
+	c.Assert(batch.Height(), Equals, 1)
+	c.Assert(batch.Headers(), DeepEquals, []string{"Maker", "Model", "Year", "Car"})
+	c.Assert(batch.Column("Car")[0], Equals, "Ferrari 458")
+	_, err = r.NextBatch(10)
+	c.Assert(err, Equals, io.EOF)
+	c.Assert(r.Err(), Equals, nil)
+
+	r, _ = tablib.NewCSVReader(bytes.NewBufferString("Maker\nPorsche\nSkoda\nFerrari"))
+	r.SkipInvalid = true
+	r.ConstrainColumn("Maker", func(val interface{}) bool { return val.(string) != "Skoda" })
+	batch, _ = r.NextBatch(5)
+	c.Assert(batch.Column("Maker"), DeepEquals, []interface{}{"Porsche", "Ferrari"})
+}
+
+func (s *TablibSuite) TestCSVWriter(c *C) {
+	var b bytes.Buffer
+	w := tablib.NewCSVWriter(&b, []string{"firstName", "lastName", "gpa"})
+	w.AppendDynamicColumn("initials", func(row []interface{}) interface{} {
+		return row[0].(string)[:1] + row[1].(string)[:1]
+	})
+	w.ConstrainColumn("gpa", mustBeOld)
+	c.Assert(w.AppendValues("Jacques", "Chirac", 88), Equals, nil)
+	c.Assert(w.AppendValues("François", "Hollande", 34), Equals, tablib.ErrInvalidDataset)
+	c.Assert(w.AppendValues("Nicolas", "Sarkozy"), Equals, tablib.ErrInvalidDimensions)
+	c.Assert(w.Flush(), Equals, nil)
+	c.Assert(b.String(), Equals, `firstName,lastName,gpa,initials
+Jacques,Chirac,88,JC
+`)
+}
+
 func (s *TablibSuite) TestLoadXML(c *C) {
 	var b bytes.Buffer
 	b.WriteString(`<dataset>
@@ -705,6 +2230,123 @@ func (s *TablibSuite) TestLoadXML(c *C) {
 	c.Assert(r["lastName"], Equals, "Washington")
 }
 
+func (s *TablibSuite) TestLoadKeepsColumnOrder(c *C) {
+	ds, err := tablib.LoadJSON([]byte(`[
+	  {"zip":"75001","name":"Paris","id":1},
+	  {"zip":"69001","name":"Lyon","country":"FR"}
+	]`))
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"zip", "name", "id", "country"})
+	r, _ := ds.Row(1)
+	c.Assert(r["id"], Equals, nil)
+
+	ds, err = tablib.LoadYAMLWithOptions([]byte(`- zip: "75001"
+  name: Paris
+- name: Lyon
+  country: FR
+`), tablib.LoadOptions{MissingValue: "N/A"})
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"zip", "name", "country"})
+	r, _ = ds.Row(0)
+	c.Assert(r["country"], Equals, "N/A")
+	r, _ = ds.Row(1)
+	c.Assert(r["zip"], Equals, "N/A")
+
+	ds, err = tablib.LoadXML([]byte(`<dataset>
+	  <row><zip>75001</zip><name>Paris</name></row>
+	  <row><name>Lyon</name><country>FR</country></row>
+	</dataset>`))
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"zip", "name", "country"})
+	r, _ = ds.Row(1)
+	c.Assert(r["country"], Equals, "FR")
+}
+
+func (s *TablibSuite) TestXLSXTypedCells(c *C) {
+	ds := tablib.NewDataset([]string{"Maker", "Year", "Share", "Electric", "Released"})
+	ds.AppendValues("Porsche", 2012, 0.125, false, time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC))
+	ds.AppendValues("Tesla", nil, 0.5, true, time.Date(2015, 9, 29, 10, 30, 0, 0, time.UTC))
+	ds.SetColumnFormat("Share", "0.00%")
+	c.Assert(ds.ColumnFormat("Share"), Equals, "0.00%")
+	x, err := ds.XLSX()
+	c.Assert(err, Equals, nil)
+
+	file, err := xlsx.OpenBinary(x.Bytes())
+	c.Assert(err, Equals, nil)
+	rows := file.Sheets[0].Rows
+	c.Assert(rows[0].Cells[1].Value, Equals, "Year")
+	c.Assert(rows[1].Cells[0].Type(), Equals, xlsx.CellTypeString)
+	c.Assert(rows[1].Cells[1].Type(), Equals, xlsx.CellTypeNumeric)
+	c.Assert(rows[1].Cells[2].GetNumberFormat(), Equals, "0.00%")
+	c.Assert(rows[1].Cells[3].Type(), Equals, xlsx.CellTypeBool)
+	c.Assert(rows[1].Cells[4].GetNumberFormat(), Equals, "yyyy-mm-dd")
+	c.Assert(rows[2].Cells[4].GetNumberFormat(), Equals, "yyyy-mm-dd hh:mm:ss")
+
+	loaded, err := tablib.LoadXLSX(x.Bytes())
+	c.Assert(err, Equals, nil)
+	r, _ := loaded.Row(1)
+	c.Assert(r["Year"], Equals, nil)
+	c.Assert(r["Share"], Equals, 0.5)
+	c.Assert(r["Electric"], Equals, true)
+	c.Assert(r["Released"], Equals, time.Date(2015, 9, 29, 10, 30, 0, 0, time.UTC))
+
+	ds = tablib.NewDataset([]string{"Share", "Note"})
+	ds.AppendValues(float32(0.1), nil)
+	ds.EmptyValue = "N/A"
+	x, err = ds.XLSX()
+	c.Assert(err, Equals, nil)
+	file, err = xlsx.OpenBinary(x.Bytes())
+	c.Assert(err, Equals, nil)
+	c.Assert(file.Sheets[0].Rows[1].Cells[0].Value, Equals, "0.1")
+	c.Assert(file.Sheets[0].Rows[1].Cells[1].Value, Equals, "N/A")
+}
+
+func (s *TablibSuite) TestLoadXLSX(c *C) {
+	file := xlsx.NewFile()
+	sheet, _ := file.AddSheet("Cars")
+	sheet.AddRow().AddCell().SetString("Exported cars")
+	row := sheet.AddRow()
+	for _, h := range []string{"Maker", "Year", "Price", "Electric", "Released"} {
+		row.AddCell().SetString(h)
+	}
+	row = sheet.AddRow()
+	row.AddCell().SetString("Porsche")
+	row.AddCell().SetInt64(2012)
+	row.AddCell().SetFloat(89000.5)
+	row.AddCell().SetBool(false)
+	row.AddCell().SetDateTime(time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC))
+	row = sheet.AddRow()
+	row.AddCell().SetString("Tesla")
+	sheet.AddRow()
+	file.AddSheet("Empty")
+	var b bytes.Buffer
+	file.Write(&b)
+
+	ds, err := tablib.LoadXLSXWithOptions(b.Bytes(), tablib.XLSXOptions{Sheet: "Cars",
+		HeaderRow: 1, SkipEmptyTrailingRows: true})
+	c.Assert(err, Equals, nil)
+	c.Assert(ds.Headers(), DeepEquals, []string{"Maker", "Year", "Price", "Electric", "Released"})
+	c.Assert(ds.Height(), Equals, 2)
+	r, _ := ds.Row(0)
+	c.Assert(r["Year"], Equals, int64(2012))
+	c.Assert(r["Price"], Equals, 89000.5)
+	c.Assert(r["Electric"], Equals, false)
+	c.Assert(r["Released"], Equals, time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC))
+	r, _ = ds.Row(1)
+	c.Assert(r["Maker"], Equals, "Tesla")
+	c.Assert(r["Year"], Equals, nil)
+
+	_, err = tablib.LoadXLSXWithOptions(b.Bytes(), tablib.XLSXOptions{SheetIndex: 2})
+	c.Assert(err, Equals, tablib.ErrInvalidSheet)
+
+	db, err := tablib.LoadDatabookXLSX(b.Bytes())
+	c.Assert(err, Equals, nil)
+	c.Assert(db.Size(), Equals, 2)
+	c.Assert(db.Sheet("Cars").Dataset().Headers(), DeepEquals, []string{"Exported cars", "Column 2",
+		"Column 3", "Column 4", "Column 5"})
+	c.Assert(db.Sheet("Empty").Dataset().Height(), Equals, 0)
+}
+
 func (s *TablibSuite) TestXML(c *C) {
 	ds := presidentDataset()
 	xml, err := ds.XML()
@@ -1,31 +1,167 @@
 package tablib
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"time"
 )
 
-// internalLoadFromDict creates a Dataset from an array of map representing columns.
-func internalLoadFromDict(input []map[string]interface{}) (*Dataset, error) {
+// LoadOptions holds the options used when loading a Dataset or a Databook
+// from a document made of records (JSON, YAML, XML).
+type LoadOptions struct {
+	// MissingValue is the value used for cells whose key is absent from a record.
+	MissingValue interface{}
+	// Tags restores the tags of the rows from the TagsColumn column, if any,
+	// which is then removed.
+	Tags bool
+	// Schema, if set, is attached to the loaded Datasets, see Dataset.SetSchema.
+	Schema Schema
+}
+
+// orderedRecord represents a record whose keys are kept in the order
+// they appear in the source document.
+type orderedRecord struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newOrderedRecord creates a new empty orderedRecord.
+func newOrderedRecord() *orderedRecord {
+	return &orderedRecord{make([]string, 0, 10), make(map[string]interface{})}
+}
+
+// set sets the value of a key, appending the key if it was not already known.
+func (r *orderedRecord) set(key string, value interface{}) {
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = value
+}
+
+// internalLoadFromDict creates a Dataset from an array of records.
+// The headers are the union of the keys of all the records, in the order
+// they are first encountered, missing cells are set to opts.MissingValue.
+func internalLoadFromDict(input []*orderedRecord, opts LoadOptions) (*Dataset, error) {
 	// retrieve columns
 	headers := make([]string, 0, 10)
-	for h := range input[0] {
-		headers = append(headers, h)
+	known := make(map[string]bool)
+	for _, r := range input {
+		for _, h := range r.keys {
+			if !known[h] {
+				known[h] = true
+				headers = append(headers, h)
+			}
+		}
 	}
 
 	ds := NewDataset(headers)
 	for _, e := range input {
 		row := make([]interface{}, 0, len(headers))
 		for _, h := range headers {
-			row = append(row, e[h])
+			if v, ok := e.values[h]; ok {
+				row = append(row, v)
+			} else if opts.Tags && h == TagsColumn {
+				// a missing TagsColumn means no tags
+				row = append(row, nil)
+			} else {
+				row = append(row, opts.MissingValue)
+			}
 		}
 		ds.AppendValues(row...)
 	}
+	if opts.Tags {
+		ds.restoreTags()
+	}
+	if opts.Schema != nil {
+		if err := ds.SetSchema(opts.Schema); err != nil {
+			return nil, err
+		}
+	}
 
 	return ds, nil
 }
 
+// evaluatedRow returns a copy of a row where dynamic columns are evaluated.
+func evaluatedRow(row []interface{}) []interface{} {
+	values := make([]interface{}, len(row))
+	for j, v := range row {
+		if fn, ok := v.(DynamicColumn); ok {
+			values[j] = fn(row)
+		} else {
+			values[j] = v
+		}
+	}
+	return values
+}
+
+// hashKey returns a string identifying a set of values, usable as a map key.
+// Numbers having the same value are identified the same whatever their type,
+// so that 1, int64(1) and 1.0 share the same key.
+// Each value is prefixed by its kind and length so that no two sets collide.
+func hashKey(values ...interface{}) string {
+	var b bytes.Buffer
+	for _, v := range values {
+		kind, s := "n", ""
+		switch v := v.(type) {
+		case nil:
+		case string:
+			kind, s = "s", v
+		case bool:
+			kind, s = "b", strconv.FormatBool(v)
+		case time.Time:
+			kind, s = "t", strconv.FormatInt(v.Unix(), 10)+"."+strconv.Itoa(v.Nanosecond())
+		case int, int8, int16, int32, int64:
+			kind, s = "i", strconv.FormatInt(reflect.ValueOf(v).Int(), 10)
+		case uint, uint8, uint16, uint32, uint64:
+			kind, s = "i", strconv.FormatUint(reflect.ValueOf(v).Uint(), 10)
+		case float32, float64:
+			f := reflect.ValueOf(v).Float()
+			if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+				// float64(math.MaxInt64) is 2^63, the first float above the range
+				kind, s = "i", strconv.FormatInt(int64(f), 10)
+			} else if f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 {
+				kind, s = "i", strconv.FormatUint(uint64(f), 10)
+			} else {
+				kind, s = "f", strconv.FormatFloat(f, 'g', -1, 64)
+			}
+		default:
+			kind, s = fmt.Sprintf("%T", v), fmt.Sprint(v)
+		}
+		b.WriteString(strconv.Itoa(len(kind)) + ":" + kind + strconv.Itoa(len(s)) + ":" + s)
+	}
+	return b.String()
+}
+
+// toFloat converts a numeric value to a float64, returning false if the
+// value is not numeric.
+func toFloat(v interface{}) (float64, bool) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64:
+		return float64(reflect.ValueOf(v).Int()), true
+	case uint, uint8, uint16, uint32, uint64:
+		return float64(reflect.ValueOf(v).Uint()), true
+	case float32, float64:
+		return reflect.ValueOf(v).Float(), true
+	}
+	return 0, false
+}
+
+// toInt64 converts an integer value to an int64, returning false if the value
+// is not an integer or overflows an int64.
+func toInt64(v interface{}) (int64, bool) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64:
+		return reflect.ValueOf(v).Int(), true
+	case uint, uint8, uint16, uint32, uint64:
+		u := reflect.ValueOf(v).Uint()
+		return int64(u), u <= math.MaxInt64
+	}
+	return 0, false
+}
+
 // isTagged checks if a tag is in an array of tags.
 func isTagged(tag string, tags []string) bool {
 	for _, t := range tags {
@@ -36,6 +172,16 @@ func isTagged(tag string, tags []string) bool {
 	return false
 }
 
+// containsString returns whether a string is part of a list.
+func containsString(s string, values []string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // asString returns a value as a string.
 func (d *Dataset) asString(vv interface{}) string {
 	var v string
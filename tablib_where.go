@@ -0,0 +1,175 @@
+package tablib
+
+import (
+	"regexp"
+)
+
+// Row represents a row of a Dataset as a map of values by header,
+// dynamic columns being evaluated.
+type Row map[string]interface{}
+
+// Predicate represents a condition on a row of a Dataset.
+type Predicate func(row Row) bool
+
+// And returns a predicate matching the rows matched by p and all the others.
+func (p Predicate) And(others ...Predicate) Predicate {
+	return func(row Row) bool {
+		if !p(row) {
+			return false
+		}
+		for _, o := range others {
+			if !o(row) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a predicate matching the rows matched by p or any of the others.
+func (p Predicate) Or(others ...Predicate) Predicate {
+	return func(row Row) bool {
+		if p(row) {
+			return true
+		}
+		for _, o := range others {
+			if o(row) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a predicate matching the rows not matched by p.
+func (p Predicate) Not() Predicate {
+	return func(row Row) bool {
+		return !p(row)
+	}
+}
+
+// ColumnRef references a column by its header, to build predicates on its values.
+// Values are compared as defined by Compare. Ordering predicates (Gt, Ge, Lt,
+// Le, Between) only match values of the same kind as their operands, so that
+// nil values or strings never match Gt(30).
+type ColumnRef struct {
+	header string
+}
+
+// Col returns a reference to a column, to build predicates on its values.
+func Col(header string) ColumnRef {
+	return ColumnRef{header}
+}
+
+// Eq matches the rows whose value equals v.
+func (c ColumnRef) Eq(v interface{}) Predicate {
+	return func(row Row) bool {
+		return Compare(row[c.header], v) == 0
+	}
+}
+
+// Ne matches the rows whose value does not equal v.
+func (c ColumnRef) Ne(v interface{}) Predicate {
+	return c.Eq(v).Not()
+}
+
+// Gt matches the rows whose value is greater than v.
+func (c ColumnRef) Gt(v interface{}) Predicate {
+	return c.compare(v, func(cmp int) bool { return cmp > 0 })
+}
+
+// Ge matches the rows whose value is greater than or equal to v.
+func (c ColumnRef) Ge(v interface{}) Predicate {
+	return c.compare(v, func(cmp int) bool { return cmp >= 0 })
+}
+
+// Lt matches the rows whose value is lower than v.
+func (c ColumnRef) Lt(v interface{}) Predicate {
+	return c.compare(v, func(cmp int) bool { return cmp < 0 })
+}
+
+// Le matches the rows whose value is lower than or equal to v.
+func (c ColumnRef) Le(v interface{}) Predicate {
+	return c.compare(v, func(cmp int) bool { return cmp <= 0 })
+}
+
+// Between matches the rows whose value is between lower and upper, inclusive.
+func (c ColumnRef) Between(lower, upper interface{}) Predicate {
+	return c.Ge(lower).And(c.Le(upper))
+}
+
+// In matches the rows whose value equals one of the given values.
+func (c ColumnRef) In(values ...interface{}) Predicate {
+	return func(row Row) bool {
+		for _, v := range values {
+			if Compare(row[c.header], v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsNil matches the rows whose value is nil.
+func (c ColumnRef) IsNil() Predicate {
+	return func(row Row) bool {
+		return row[c.header] == nil
+	}
+}
+
+// NotNil matches the rows whose value is not nil.
+func (c ColumnRef) NotNil() Predicate {
+	return c.IsNil().Not()
+}
+
+// Matches matches the rows whose value is a string matching a regular expression.
+func (c ColumnRef) Matches(re *regexp.Regexp) Predicate {
+	return func(row Row) bool {
+		s, ok := row[c.header].(string)
+		return ok && re.MatchString(s)
+	}
+}
+
+// compare returns a predicate matching the values of the same kind as v for
+// which accept returns true given the result of Compare.
+func (c ColumnRef) compare(v interface{}, accept func(int) bool) Predicate {
+	return func(row Row) bool {
+		value := row[c.header]
+		return kindOf(value) == kindOf(v) && accept(Compare(value, v))
+	}
+}
+
+// Where returns a new Dataset including only the rows matching a predicate.
+// Tags and constraints are conserved.
+func (d *Dataset) Where(p Predicate) *Dataset {
+	nd := NewDataset(d.headers)
+	d.copyConstraintsTo(nd)
+	for i, r := range d.data {
+		if p(d.rowAsMap(r)) {
+			nd.AppendTagged(r, d.tags[i]...)
+		}
+	}
+	return nd
+}
+
+// TagWhere tags in place the rows matching a predicate with specific tags.
+// Returns the number of matching rows.
+func (d *Dataset) TagWhere(p Predicate, tags ...string) int {
+	count := 0
+	for i, r := range d.data {
+		if p(d.rowAsMap(r)) {
+			d.Tag(i, tags...)
+			count++
+		}
+	}
+	return count
+}
+
+// rowAsMap returns a row as a Row, dynamic columns being evaluated.
+func (d *Dataset) rowAsMap(r []interface{}) Row {
+	row := make(Row, d.cols)
+	for j, v := range evaluatedRow(r) {
+		row[d.headers[j]] = v
+	}
+	return row
+}
@@ -1,10 +1,44 @@
 package tablib
 
 import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/tealeg/xlsx"
 )
 
+const (
+	defaultXlsxIntFormat      = "0"
+	defaultXlsxFloatFormat    = "general"
+	defaultXlsxDateFormat     = "yyyy-mm-dd"
+	defaultXlsxDateTimeFormat = "yyyy-mm-dd hh:mm:ss"
+)
+
+// XLSXOptions holds the options used when loading a Dataset or a Databook
+// from a XLSX workbook.
+type XLSXOptions struct {
+	// Sheet is the name of the sheet to load as a Dataset. SheetIndex is used if empty.
+	Sheet string
+	// SheetIndex is the index of the sheet to load as a Dataset.
+	SheetIndex int
+	// HeaderRow is the index of the row holding the headers, rows above it are ignored.
+	HeaderRow int
+	// SkipEmptyTrailingRows ignores the empty rows at the end of the sheets.
+	SkipEmptyTrailingRows bool
+	// Tags restores the tags of the rows from the TagsColumn column, if any,
+	// which is then removed.
+	Tags bool
+	// Schema, if set, is attached to the loaded Datasets, see Dataset.SetSchema.
+	Schema Schema
+}
+
 // XLSX exports the Dataset as a byte array representing the .xlsx format.
+// Numbers, booleans and time.Time values are written as native Excel cells, using
+// the formats set with SetColumnFormat if any.
 func (d *Dataset) XLSX() (*Exportable, error) {
 	file := xlsx.NewFile()
 	if err := d.addXlsxSheetToFile(file, "Sheet 1"); err != nil {
@@ -12,7 +46,9 @@ func (d *Dataset) XLSX() (*Exportable, error) {
 	}
 
 	b := newBuffer()
-	file.Write(b)
+	if err := file.Write(b); err != nil {
+		return nil, err
+	}
 	return newExportable(b), nil
 }
 
@@ -21,30 +57,255 @@ func (d *Databook) XLSX() (*Exportable, error) {
 	file := xlsx.NewFile()
 
 	for _, s := range d.sheets {
-		s.dataset.addXlsxSheetToFile(file, s.title)
+		if err := s.dataset.addXlsxSheetToFile(file, s.title); err != nil {
+			return nil, err
+		}
 	}
 
 	b := newBuffer()
-	file.Write(b)
+	if err := file.Write(b); err != nil {
+		return nil, err
+	}
 	return newExportable(b), nil
 }
 
 func (d *Dataset) addXlsxSheetToFile(file *xlsx.File, sheetName string) error {
 	sheet, err := file.AddSheet(sheetName)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	back := d.Records()
-	for i, r := range back {
+	row := sheet.AddRow()
+	for _, h := range d.headers {
+		cell := row.AddCell()
+		cell.SetString(h)
+		cell.GetStyle().Font.Bold = true
+	}
+
+	for _, r := range d.data {
 		row := sheet.AddRow()
-		for _, c := range r {
-			cell := row.AddCell()
-			cell.Value = c
-			if i == 0 {
-				cell.GetStyle().Font.Bold = true
+		for j, v := range r {
+			if fn, ok := v.(DynamicColumn); ok {
+				v = fn(r)
 			}
+			d.setXlsxCell(row.AddCell(), v, d.formats[d.headers[j]], file.Date1904)
 		}
 	}
 	return nil
 }
+
+// setXlsxCell sets the value of a cell using the native Excel type matching the
+// Go type of the value, and the given number format if not empty. Dates are
+// written relative to the 1904 epoch if date1904 is set. nil values are written
+// as the EmptyValue of the Dataset.
+func (d *Dataset) setXlsxCell(cell *xlsx.Cell, value interface{}, format string, date1904 bool) {
+	switch v := value.(type) {
+	case nil:
+		if d.EmptyValue != "" {
+			cell.SetString(d.EmptyValue)
+		}
+	case bool:
+		cell.SetBool(v)
+	case time.Time:
+		if format == "" {
+			format = defaultXlsxDateTimeFormat
+			if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+				format = defaultXlsxDateFormat
+			}
+		}
+		// Excel has no time zones, keep the wall clock of the value
+		wall := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(),
+			v.Nanosecond(), time.UTC)
+		cell.SetDateTimeWithFormat(xlsx.TimeToExcelTime(wall, date1904), format)
+	case int, int8, int16, int32, int64:
+		i := reflect.ValueOf(v).Int()
+		if format == "" {
+			cell.SetInt64(i)
+		} else {
+			cell.SetFloatWithFormat(float64(i), format)
+		}
+	case uint, uint8, uint16, uint32, uint64:
+		u := reflect.ValueOf(v).Uint()
+		if format == "" && u <= math.MaxInt64 {
+			cell.SetInt64(int64(u))
+		} else {
+			cell.SetFloatWithFormat(float64(u), formatOrDefault(format, defaultXlsxIntFormat))
+		}
+	case float32:
+		// use the shortest representation of the float32, without widening noise
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		cell.SetFloatWithFormat(f, formatOrDefault(format, defaultXlsxFloatFormat))
+	case float64:
+		cell.SetFloatWithFormat(v, formatOrDefault(format, defaultXlsxFloatFormat))
+	default:
+		cell.SetString(d.asString(v))
+	}
+}
+
+// formatOrDefault returns format if not empty, def otherwise.
+func formatOrDefault(format, def string) string {
+	if format == "" {
+		return def
+	}
+	return format
+}
+
+// LoadXLSX loads a Dataset from the first sheet of a XLSX workbook.
+func LoadXLSX(input []byte) (*Dataset, error) {
+	return LoadXLSXWithOptions(input, XLSXOptions{})
+}
+
+// LoadXLSXWithOptions loads a Dataset from a sheet of a XLSX workbook using specific options.
+// Numeric cells are loaded as int64 or float64, cells formatted as dates as time.Time,
+// boolean cells as bool, empty cells as nil and other cells as string.
+// Returns ErrInvalidSheet if the sheet does not exist.
+func LoadXLSXWithOptions(input []byte, opts XLSXOptions) (*Dataset, error) {
+	file, err := xlsx.OpenBinary(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet *xlsx.Sheet
+	if opts.Sheet != "" {
+		sheet = file.Sheet[opts.Sheet]
+	} else if opts.SheetIndex >= 0 && opts.SheetIndex < len(file.Sheets) {
+		sheet = file.Sheets[opts.SheetIndex]
+	}
+	if sheet == nil {
+		return nil, ErrInvalidSheet
+	}
+
+	return loadXlsxSheet(sheet, file.Date1904, opts)
+}
+
+// LoadDatabookXLSX loads a Databook from a XLSX workbook, each sheet becoming a Dataset.
+func LoadDatabookXLSX(input []byte) (*Databook, error) {
+	return LoadDatabookXLSXWithOptions(input, XLSXOptions{})
+}
+
+// LoadDatabookXLSXWithOptions loads a Databook from a XLSX workbook using specific
+// options, each sheet becoming a Dataset. The sheet selection options are ignored.
+func LoadDatabookXLSXWithOptions(input []byte, opts XLSXOptions) (*Databook, error) {
+	file, err := xlsx.OpenBinary(input)
+	if err != nil {
+		return nil, err
+	}
+
+	db := NewDatabook()
+	for _, sheet := range file.Sheets {
+		ds, err := loadXlsxSheet(sheet, file.Date1904, opts)
+		if err != nil {
+			return nil, err
+		}
+		db.AddSheet(sheet.Name, ds)
+	}
+	return db, nil
+}
+
+// loadXlsxSheet creates a Dataset from a sheet.
+func loadXlsxSheet(sheet *xlsx.Sheet, date1904 bool, opts XLSXOptions) (*Dataset, error) {
+	rows := make([][]interface{}, 0, len(sheet.Rows))
+	width := 0
+	for i, r := range sheet.Rows {
+		if i < opts.HeaderRow {
+			continue
+		}
+		row := make([]interface{}, len(r.Cells))
+		for j, c := range r.Cells {
+			row[j] = xlsxCellValue(c, date1904)
+		}
+		rows = append(rows, row)
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	if opts.SkipEmptyTrailingRows {
+		for len(rows) > 1 && isEmptyRow(rows[len(rows)-1]) {
+			rows = rows[:len(rows)-1]
+		}
+	}
+
+	headers := make([]string, width)
+	for j := range headers {
+		if len(rows) > 0 && j < len(rows[0]) && rows[0][j] != nil {
+			headers[j] = fmt.Sprint(rows[0][j])
+		} else {
+			headers[j] = "Column " + strconv.Itoa(j+1)
+		}
+	}
+
+	ds := NewDataset(headers)
+	if len(rows) > 0 {
+		for _, r := range rows[1:] {
+			row := make([]interface{}, width)
+			copy(row, r)
+			ds.Append(row)
+		}
+	}
+	if opts.Tags {
+		ds.restoreTags()
+	}
+	if opts.Schema != nil {
+		if err := ds.SetSchema(opts.Schema); err != nil {
+			return nil, err
+		}
+	}
+	return ds, nil
+}
+
+// xlsxCellValue returns the value of a cell as a Go value.
+func xlsxCellValue(c *xlsx.Cell, date1904 bool) interface{} {
+	if c.Value == "" {
+		return nil
+	}
+
+	switch c.Type() {
+	case xlsx.CellTypeBool:
+		return c.Bool()
+	case xlsx.CellTypeNumeric:
+		if f, err := c.Float(); err == nil {
+			if isDateFormat(c.GetNumberFormat()) {
+				return xlsx.TimeFromExcelTime(f, date1904)
+			}
+			if i, err := c.Int64(); err == nil {
+				return i
+			}
+			return f
+		}
+	}
+	return c.Value
+}
+
+// isDateFormat returns whether a number format represents a date or a time,
+// that is when it contains date or time placeholders outside of quoted
+// literals and bracketed sections.
+func isDateFormat(format string) bool {
+	if strings.EqualFold(format, "general") {
+		return false
+	}
+	inQuotes, inBrackets := false, false
+	for _, r := range strings.ToLower(format) {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '[' && !inQuotes:
+			inBrackets = true
+		case r == ']' && !inQuotes:
+			inBrackets = false
+		case !inQuotes && !inBrackets && strings.ContainsRune("ymdhs", r):
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyRow returns whether all the values of a row are nil.
+func isEmptyRow(row []interface{}) bool {
+	for _, v := range row {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,9 @@ package tablib
 
 import (
 	"bytes"
+	"encoding/xml"
+	"io"
+
 	"github.com/agrison/mxj"
 )
 
@@ -46,19 +49,104 @@ func (d *Dataset) XMLWithTagNamePrefixIndent(tagName, prefix, indent string) (*E
 
 // LoadXML loads a Dataset from an XML source.
 func LoadXML(input []byte) (*Dataset, error) {
-	m, _, err := mxj.NewMapXmlReaderRaw(bytes.NewReader(input))
+	return LoadXMLWithOptions(input, LoadOptions{})
+}
+
+// LoadXMLWithOptions loads a Dataset from an XML source using specific options.
+// Every child of the root element is a row, and columns are ordered as the
+// elements first appear in the rows.
+func LoadXMLWithOptions(input []byte, opts LoadOptions) (*Dataset, error) {
+	records, err := decodeXMLRecords(input)
 	if err != nil {
 		return nil, err
 	}
 
-	// this seems quite a bit hacky
-	datasetNode, _ := m.ValueForPath("dataset")
-	rowNode := datasetNode.(map[string]interface{})["row"].([]interface{})
+	return internalLoadFromDict(records, opts)
+}
+
+// decodeXMLRecords decodes the rows of an XML document, keeping the order
+// of the elements of each row.
+func decodeXMLRecords(input []byte) ([]*orderedRecord, error) {
+	dec := xml.NewDecoder(bytes.NewReader(input))
+	depth := 0
+	records := make([]*orderedRecord, 0)
+	var current *orderedRecord
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 2: // row
+				current = newOrderedRecord()
+			case 3: // column
+				value, err := decodeXMLElement(dec)
+				if err != nil {
+					return nil, err
+				}
+				depth--
+				current.set(t.Name.Local, mergeXMLValue(current.values, t.Name.Local, value))
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				records = append(records, current)
+			}
+			depth--
+		}
+	}
+
+	return records, nil
+}
 
-	back := make([]map[string]interface{}, 0, len(rowNode))
-	for _, r := range rowNode {
-		back = append(back, r.(map[string]interface{}))
+// decodeXMLElement decodes the content of an element whose start tag has
+// just been read. It returns a string for a leaf element, or a map for an
+// element having children.
+func decodeXMLElement(dec *xml.Decoder) (interface{}, error) {
+	var text bytes.Buffer
+	var children map[string]interface{}
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := t.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			value, err := decodeXMLElement(dec)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(map[string]interface{})
+			}
+			children[t.Name.Local] = mergeXMLValue(children, t.Name.Local, value)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return text.String(), nil
+		}
 	}
+}
 
-	return internalLoadFromDict(back)
+// mergeXMLValue returns the value to store for a key, turning repeated
+// elements into a list.
+func mergeXMLValue(values map[string]interface{}, key string, value interface{}) interface{} {
+	previous, ok := values[key]
+	if !ok {
+		return value
+	}
+	if list, ok := previous.([]interface{}); ok {
+		return append(list, value)
+	}
+	return []interface{}{previous, value}
 }
@@ -1,37 +1,46 @@
 package tablib
 
-import "gopkg.in/yaml.v2"
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
 
 // LoadYAML loads a dataset from a YAML source.
 func LoadYAML(yamlContent []byte) (*Dataset, error) {
-	var input []map[string]interface{}
+	return LoadYAMLWithOptions(yamlContent, LoadOptions{})
+}
+
+// LoadYAMLWithOptions loads a dataset from a YAML source using specific options.
+// Columns are ordered as their keys first appear in the source.
+func LoadYAMLWithOptions(yamlContent []byte, opts LoadOptions) (*Dataset, error) {
+	var input []yaml.MapSlice
 	if err := yaml.Unmarshal(yamlContent, &input); err != nil {
 		return nil, err
 	}
 
-	return internalLoadFromDict(input)
+	return internalLoadFromDict(yamlRecords(input), opts)
 }
 
 // LoadDatabookYAML loads a Databook from a YAML source.
 func LoadDatabookYAML(yamlContent []byte) (*Databook, error) {
-	var input []map[string]interface{}
-	var internalInput []map[string]interface{}
+	return LoadDatabookYAMLWithOptions(yamlContent, LoadOptions{})
+}
+
+// LoadDatabookYAMLWithOptions loads a Databook from a YAML source using specific options.
+func LoadDatabookYAMLWithOptions(yamlContent []byte, opts LoadOptions) (*Databook, error) {
+	var input []struct {
+		Title string          `yaml:"title"`
+		Data  []yaml.MapSlice `yaml:"data"`
+	}
 	if err := yaml.Unmarshal(yamlContent, &input); err != nil {
 		return nil, err
 	}
 
 	db := NewDatabook()
 	for _, d := range input {
-		b, err := yaml.Marshal(d["data"])
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(b, &internalInput); err != nil {
-			return nil, err
-		}
-
-		if ds, err := internalLoadFromDict(internalInput); err == nil {
-			db.AddSheet(d["title"].(string), ds)
+		if ds, err := internalLoadFromDict(yamlRecords(d.Data), opts); err == nil {
+			db.AddSheet(d.Title, ds)
 		} else {
 			return nil, err
 		}
@@ -40,6 +49,19 @@ func LoadDatabookYAML(yamlContent []byte) (*Databook, error) {
 	return db, nil
 }
 
+// yamlRecords converts YAML ordered maps to records.
+func yamlRecords(input []yaml.MapSlice) []*orderedRecord {
+	records := make([]*orderedRecord, 0, len(input))
+	for _, m := range input {
+		r := newOrderedRecord()
+		for _, item := range m {
+			r.set(fmt.Sprintf("%v", item.Key), item.Value)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
 // YAML returns a YAML representation of the Dataset as an Exportable.
 func (d *Dataset) YAML() (*Exportable, error) {
 	back := d.Dict()